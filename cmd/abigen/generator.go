@@ -0,0 +1,450 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/uuosio/go-uuoskit/uuoskit"
+)
+
+const (
+	typeNone = iota
+	typeOptional
+	typeArray
+	typeFixedArray
+)
+
+// Go types of the built-in abi types
+var builtinTypes = map[string]string{
+	"bool":                 "bool",
+	"int8":                 "int8",
+	"uint8":                "uint8",
+	"int16":                "int16",
+	"uint16":               "uint16",
+	"int32":                "int32",
+	"uint32":               "uint32",
+	"int64":                "int64",
+	"uint64":               "uint64",
+	"int128":               "uuoskit.Int128",
+	"uint128":              "uuoskit.Uint128",
+	"varint32":             "uuoskit.VarInt32",
+	"varuint32":            "uuoskit.VarUint32",
+	"float32":              "float32",
+	"float64":              "float64",
+	"float128":             "uuoskit.Float128",
+	"time_point":           "uuoskit.TimePoint",
+	"time_point_sec":       "uuoskit.TimePointSec",
+	"block_timestamp_type": "uuoskit.BlockTimestampType",
+	"name":                 "uuoskit.Name",
+	"bytes":                "uuoskit.Bytes",
+	"string":               "string",
+	"checksum160":          "[20]byte",
+	"checksum256":          "[32]byte",
+	"checksum512":          "[64]byte",
+	"public_key":           "uuoskit.PublicKey",
+	"signature":            "uuoskit.Signature",
+	"symbol":               "uuoskit.Symbol",
+	"symbol_code":          "uint64",
+	"asset":                "uuoskit.Asset",
+	"extended_asset":       "uuoskit.ExtendedAsset",
+}
+
+// parseType splits the outermost modifier off typ, as the abi serializer does
+func parseType(typ string) (kind int, elem string, size int) {
+	if strings.HasSuffix(typ, "?") {
+		return typeOptional, typ[:len(typ)-1], 0
+	}
+	if !strings.HasSuffix(typ, "]") {
+		return typeNone, typ, 0
+	}
+
+	pos := strings.LastIndex(typ, "[")
+	if pos <= 0 {
+		return typeNone, typ, 0
+	}
+	if pos == len(typ)-2 {
+		return typeArray, typ[:pos], 0
+	}
+
+	n, err := strconv.ParseUint(typ[pos+1:len(typ)-1], 10, 32)
+	if err != nil || n == 0 {
+		return typeNone, typ, 0
+	}
+	return typeFixedArray, typ[:pos], int(n)
+}
+
+// goName converts an abi name like account_v1 or string[] to a Go identifier like AccountV1 or StringArray
+func goName(name string) string {
+	name = strings.Replace(name, "[]", "_array", -1)
+	name = strings.Replace(name, "[", "_array", -1)
+	name = strings.Replace(name, "?", "_optional", -1)
+
+	var b strings.Builder
+	upper := true
+	for _, c := range name {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			upper = true
+			continue
+		}
+		if upper {
+			c = unicode.ToUpper(c)
+			upper = false
+		}
+		b.WriteRune(c)
+	}
+
+	s := b.String()
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		s = "T" + s
+	}
+	return s
+}
+
+// methods of the generated types, fields with these names are renamed
+var generatedMethods = []string{"Pack", "PackE", "Unpack", "Size"}
+
+type generator struct {
+	abi *uuoskit.ABI
+	buf bytes.Buffer
+	//depth of the generated loops, for the names of the index variables
+	depth int
+}
+
+// generate returns the Go code of the types and actions of abi in package pkg
+func generate(abi *uuoskit.ABI, pkg string) ([]byte, error) {
+	if err := abi.Validate(); err != nil {
+		return nil, err
+	}
+
+	g := &generator{abi: abi}
+	if err := g.checkNames(); err != nil {
+		return nil, err
+	}
+
+	for _, t := range abi.Types {
+		g.printf("type %s = %s\n\n", goName(t.NewTypeName), g.goType(t.Type))
+	}
+	for i := range abi.Structs {
+		g.genStruct(&abi.Structs[i])
+	}
+	for i := range abi.Variants {
+		g.genVariant(&abi.Variants[i])
+	}
+	for _, table := range abi.Tables {
+		name := goName(table.Name)
+		g.printf("// %sTable is the name of the table %s\n", name, table.Name)
+		g.printf("var %sTable = uuoskit.NewName(%q)\n\n", name, table.Name)
+		g.printf("// %sRow is a row of the table %s\n", name, table.Name)
+		g.printf("type %sRow = %s\n\n", name, g.goType(table.Type))
+	}
+	for _, action := range abi.Actions {
+		name := goName(action.Name)
+		g.printf("// New%sAction creates the action %s of the contract deployed to contract\n", name, action.Name)
+		g.printf("func New%sAction(contract uuoskit.Name, authorization []uuoskit.PermissionLevel, args *%s) (*uuoskit.Action, error) {\n", name, g.goType(action.Type))
+		g.printf("data, err := args.PackE()\nif err != nil {\nreturn nil, err\n}\n")
+		g.printf("return &uuoskit.Action{Account: contract, Name: uuoskit.NewName(%q), Authorization: authorization, Data: data}, nil\n", action.Name)
+		g.printf("}\n\n")
+	}
+
+	body := g.buf.String()
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by abigen. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	imports := []string{}
+	if strings.Contains(body, "fmt.") {
+		imports = append(imports, `"fmt"`)
+	}
+	if strings.Contains(body, "uuoskit.") {
+		imports = append(imports, `"github.com/uuosio/go-uuoskit/uuoskit"`)
+	}
+	if len(imports) > 0 {
+		fmt.Fprintf(&out, "import (\n%s\n)\n\n", strings.Join(imports, "\n\n"))
+	}
+	out.WriteString(body)
+
+	code, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %v", err)
+	}
+	return code, nil
+}
+
+// checkNames returns an error if two abi names are generated as the same Go identifier
+func (g *generator) checkNames() error {
+	names := make(map[string]string)
+	add := func(ident string, abiName string) error {
+		if other, ok := names[ident]; ok {
+			return fmt.Errorf("%s and %s are both generated as %s", other, abiName, ident)
+		}
+		names[ident] = abiName
+		return nil
+	}
+
+	for _, t := range g.abi.Types {
+		if err := add(goName(t.NewTypeName), "type "+t.NewTypeName); err != nil {
+			return err
+		}
+	}
+	for _, s := range g.abi.Structs {
+		if err := add(goName(s.Name), "struct "+s.Name); err != nil {
+			return err
+		}
+	}
+	for _, v := range g.abi.Variants {
+		if err := add(goName(v.Name), "variant "+v.Name); err != nil {
+			return err
+		}
+		for _, typ := range v.Types {
+			if err := add("New"+goName(v.Name)+goName(typ), "variant "+v.Name+" type "+typ); err != nil {
+				return err
+			}
+		}
+	}
+	for _, table := range g.abi.Tables {
+		if err := add(goName(table.Name)+"Table", "table "+table.Name); err != nil {
+			return err
+		}
+		if err := add(goName(table.Name)+"Row", "table "+table.Name); err != nil {
+			return err
+		}
+	}
+	for _, action := range g.abi.Actions {
+		if err := add("New"+goName(action.Name)+"Action", "action "+action.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldNames returns the Go names of the fields of s, a field that clashes with the embedded base,
+// a generated method or an earlier field gets trailing underscores
+func fieldNames(s *uuoskit.ABIStruct) []string {
+	used := make(map[string]bool)
+	for _, method := range generatedMethods {
+		used[method] = true
+	}
+	if s.Base != "" {
+		used[goName(s.Base)] = true
+	}
+
+	names := make([]string, len(s.Fields))
+	for i, field := range s.Fields {
+		name := goName(field.Name)
+		for used[name] {
+			name += "_"
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// resolve follows typedefs of typ
+func (g *generator) resolve(typ string) string {
+	if base, ok := g.abi.GetBaseABIType(typ); ok {
+		return base
+	}
+	return typ
+}
+
+func (g *generator) goType(typ string) string {
+	kind, elem, size := parseType(typ)
+	switch kind {
+	case typeOptional:
+		return "*" + g.goType(elem)
+	case typeArray:
+		return "[]" + g.goType(elem)
+	case typeFixedArray:
+		return fmt.Sprintf("[%d]%s", size, g.goType(elem))
+	}
+	if t, ok := builtinTypes[typ]; ok {
+		return t
+	}
+	return goName(typ)
+}
+
+func (g *generator) index() string {
+	g.depth++
+	return fmt.Sprintf("i%d", g.depth)
+}
+
+// genPack prints the code that packs expr of type typ with enc
+func (g *generator) genPack(expr string, typ string) {
+	typ = g.resolve(typ)
+	kind, elem, _ := parseType(typ)
+	switch kind {
+	case typeOptional:
+		g.printf("if %s == nil {\nenc.PackBool(false)\n} else {\nenc.PackBool(true)\n", expr)
+		g.genPack("(*"+expr+")", elem)
+		g.printf("}\n")
+		return
+	case typeArray, typeFixedArray:
+		if kind == typeArray {
+			g.printf("enc.PackLength(len(%s))\n", expr)
+		}
+		i := g.index()
+		g.printf("for %s := range %s {\n", i, expr)
+		g.genPack(fmt.Sprintf("%s[%s]", expr, i), elem)
+		g.printf("}\n")
+		g.depth--
+		return
+	}
+
+	switch typ {
+	case "bool", "int8", "uint8", "int16", "uint16", "int32", "uint32", "int64", "uint64", "float32", "float64", "string", "symbol_code":
+		g.printf("enc.Pack(%s)\n", expr)
+	case "bytes":
+		g.printf("enc.PackBytes(%s)\n", expr)
+	case "checksum160", "checksum256", "checksum512":
+		g.printf("enc.WriteBytes(%s[:])\n", expr)
+	default:
+		if _, ok := builtinTypes[typ]; ok {
+			g.printf("enc.Write(%s.Pack())\n", expr)
+		} else {
+			//structs and variants return the errors of variants
+			g.printf("if b, err := %s.PackE(); err != nil {\nreturn nil, err\n} else {\nenc.Write(b)\n}\n", expr)
+		}
+	}
+}
+
+// genPackMethod prints the Pack method of name that panics on the errors of PackE
+func (g *generator) genPackMethod(name string) {
+	g.printf("func (t *%s) Pack() []byte {\nb, err := t.PackE()\nif err != nil {\npanic(err)\n}\nreturn b\n}\n\n", name)
+}
+
+// genUnpack prints the code that unpacks expr of type typ with dec
+func (g *generator) genUnpack(expr string, typ string) {
+	typ = g.resolve(typ)
+	kind, elem, _ := parseType(typ)
+	switch kind {
+	case typeOptional:
+		g.printf("if present, err := dec.UnpackBool(); err != nil {\nreturn 0, err\n} else if !present {\n%s = nil\n} else {\n", expr)
+		g.printf("%s = new(%s)\n", expr, g.goType(elem))
+		g.genUnpack("(*"+expr+")", elem)
+		g.printf("}\n")
+		return
+	case typeArray:
+		//elements like structs without fields take no bytes, only the capacity is bounded by the remaining bytes
+		g.printf("if n, err := dec.UnpackLength(); err != nil {\nreturn 0, err\n} else {\n")
+		g.printf("c := n\nif r := len(dec.Remains()); c > r {\nc = r\n}\n%s = make(%s, 0, c)\n", expr, g.goType(typ))
+		i := g.index()
+		g.printf("for %s := 0; %s < n; %s++ {\n%s = append(%s, *new(%s))\n", i, i, i, expr, expr, g.goType(elem))
+		g.genUnpack(fmt.Sprintf("%s[%s]", expr, i), elem)
+		g.printf("}\n}\n")
+		g.depth--
+		return
+	case typeFixedArray:
+		i := g.index()
+		g.printf("for %s := range %s {\n", i, expr)
+		g.genUnpack(fmt.Sprintf("%s[%s]", expr, i), elem)
+		g.printf("}\n")
+		g.depth--
+		return
+	}
+
+	switch typ {
+	case "checksum160", "checksum256", "checksum512":
+		g.printf("if err := dec.Read(%s[:]); err != nil {\nreturn 0, err\n}\n", expr)
+	default:
+		g.printf("if _, err := dec.Unpack(&%s); err != nil {\nreturn 0, err\n}\n", expr)
+	}
+}
+
+func (g *generator) genStruct(s *uuoskit.ABIStruct) {
+	name := goName(s.Name)
+	fields := fieldNames(s)
+	g.printf("// %s is the struct %s\n", name, s.Name)
+	g.printf("type %s struct {\n", name)
+	if s.Base != "" {
+		g.printf("%s\n", goName(s.Base))
+	}
+	for i, field := range s.Fields {
+		if strings.HasSuffix(field.Type, "$") {
+			g.printf("%s *%s `json:\"%s,omitempty\"`\n", fields[i], g.goType(strings.TrimSuffix(field.Type, "$")), field.Name)
+		} else {
+			g.printf("%s %s `json:\"%s\"`\n", fields[i], g.goType(field.Type), field.Name)
+		}
+	}
+	g.printf("}\n\n")
+
+	g.printf("// PackE packs t, it returns an error if the value of a variant does not match its index\n")
+	g.printf("func (t *%s) PackE() ([]byte, error) {\n", name)
+	g.printf("enc := uuoskit.NewEncoder(64)\n")
+	if s.Base != "" {
+		g.genPack("t."+goName(s.Base), s.Base)
+	}
+	for i, field := range s.Fields {
+		expr := "t." + fields[i]
+		if strings.HasSuffix(field.Type, "$") {
+			//binary extensions after the first one left out are left out too
+			g.printf("if %s == nil {\nreturn enc.GetBytes(), nil\n}\n", expr)
+			g.genPack("(*"+expr+")", strings.TrimSuffix(field.Type, "$"))
+		} else {
+			g.genPack(expr, field.Type)
+		}
+	}
+	g.printf("return enc.GetBytes(), nil\n}\n\n")
+	g.genPackMethod(name)
+
+	g.printf("func (t *%s) Unpack(data []byte) (int, error) {\n", name)
+	g.printf("dec := uuoskit.NewDecoder(data)\n")
+	if s.Base != "" {
+		g.printf("if _, err := dec.Unpack(&t.%s); err != nil {\nreturn 0, err\n}\n", goName(s.Base))
+	}
+	for i, field := range s.Fields {
+		expr := "t." + fields[i]
+		if strings.HasSuffix(field.Type, "$") {
+			typ := strings.TrimSuffix(field.Type, "$")
+			g.printf("if dec.IsEnd() {\nreturn dec.Pos(), nil\n}\n")
+			g.printf("%s = new(%s)\n", expr, g.goType(typ))
+			g.genUnpack("(*"+expr+")", typ)
+		} else {
+			g.genUnpack(expr, field.Type)
+		}
+	}
+	g.printf("return dec.Pos(), nil\n}\n\n")
+
+	g.printf("func (t *%s) Size() int {\nreturn len(t.Pack())\n}\n\n", name)
+}
+
+func (g *generator) genVariant(v *uuoskit.VariantDef) {
+	name := goName(v.Name)
+	g.printf("// %s is the variant %s of %s, Value is of the Go type of the abi type at Index\n", name, v.Name, strings.Join(v.Types, ", "))
+	g.printf("type %s struct {\nIndex uint32\nValue interface{}\n}\n\n", name)
+
+	for i, typ := range v.Types {
+		g.printf("func New%s%s(v %s) %s {\nreturn %s{%d, v}\n}\n\n", name, goName(typ), g.goType(typ), name, name, i)
+	}
+
+	g.printf("// PackE packs t, it returns an error if Value does not match Index\n")
+	g.printf("func (t *%s) PackE() ([]byte, error) {\n", name)
+	g.printf("enc := uuoskit.NewEncoder(64)\nenc.PackVarUint32(t.Index)\nswitch t.Index {\n")
+	for i, typ := range v.Types {
+		g.printf("case %d:\nv, ok := t.Value.(%s)\n", i, g.goType(typ))
+		g.printf("if !ok {\nreturn nil, fmt.Errorf(\"invalid %s value %%T for %s\", t.Value)\n}\n", v.Name, typ)
+		g.genPack("v", typ)
+	}
+	g.printf("default:\nreturn nil, fmt.Errorf(\"invalid %s index %%d\", t.Index)\n}\n", v.Name)
+	g.printf("return enc.GetBytes(), nil\n}\n\n")
+	g.genPackMethod(name)
+
+	g.printf("func (t *%s) Unpack(data []byte) (int, error) {\n", name)
+	g.printf("dec := uuoskit.NewDecoder(data)\nindex, err := dec.UnpackVarUint32()\nif err != nil {\nreturn 0, err\n}\n")
+	g.printf("t.Index = uint32(index)\nswitch t.Index {\n")
+	for i, typ := range v.Types {
+		g.printf("case %d:\nvar v %s\n", i, g.goType(typ))
+		g.genUnpack("v", typ)
+		g.printf("t.Value = v\n")
+	}
+	g.printf("default:\nreturn 0, fmt.Errorf(\"invalid %s index %%d\", index)\n}\n", v.Name)
+	g.printf("return dec.Pos(), nil\n}\n\n")
+
+	g.printf("func (t *%s) Size() int {\nreturn len(t.Pack())\n}\n\n", name)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uuosio/go-uuoskit/uuoskit"
+)
+
+var testAbi = `{
+	"version": "eosio::abi/1.1",
+	"types": [{"new_type_name": "account_name", "type": "name"}, {"new_type_name": "ids", "type": "uint16[]"}],
+	"structs": [
+		{"name": "point", "base": "", "fields": [{"name": "x", "type": "int32"}, {"name": "y", "type": "int32"}]},
+		{"name": "base_record", "base": "", "fields": [{"name": "id", "type": "uint64"}]},
+		{"name": "list", "base": "", "fields": [{"name": "values", "type": "uint64[]"}]},
+		{"name": "empty", "base": "", "fields": []},
+		{"name": "empties", "base": "", "fields": [{"name": "items", "type": "empty[]"}, {"name": "grid", "type": "uint16[][]"}]},
+		{"name": "record", "base": "base_record", "fields": [
+			{"name": "owner", "type": "account_name"},
+			{"name": "balance", "type": "asset"},
+			{"name": "ids", "type": "ids"},
+			{"name": "memo", "type": "string?"},
+			{"name": "hash", "type": "checksum256"},
+			{"name": "points", "type": "point[2]"},
+			{"name": "nested", "type": "point?[]"},
+			{"name": "shape", "type": "shape"},
+			{"name": "delta", "type": "varint32"},
+			{"name": "data", "type": "bytes"},
+			{"name": "ext", "type": "uint8$"}
+		]}
+	],
+	"actions": [{"name": "record", "type": "record", "ricardian_contract": ""}],
+	"tables": [{"name": "records", "type": "record", "index_type": "i64", "key_names": [], "key_types": []}],
+	"variants": [{"name": "shape", "types": ["uint16", "point"]}]
+}`
+
+// packs record with the generated code, unpacks it and prints the packed bytes, the packed action data
+// and the bytes packed again after unpacking
+var testProgram = `package main
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/uuosio/go-uuoskit/uuoskit"
+)
+
+func main() {
+	memo := "hello"
+	record := Record{
+		BaseRecord: BaseRecord{Id: 1},
+		Owner:      uuoskit.NewName("alice"),
+		Balance:    *uuoskit.NewAsset(10000, uuoskit.NewSymbol("EOS", 4)),
+		Ids:        Ids{1, 2},
+		Memo:       &memo,
+		Points:     [2]Point{{1, 2}, {3, 4}},
+		Nested:     []*Point{nil, {5, 6}},
+		Shape:      NewShapePoint(Point{7, 8}),
+		Delta:      -2,
+		Data:       uuoskit.Bytes{0xff},
+	}
+	record.Hash[0] = 0xaa
+	packed := record.Pack()
+	fmt.Println(hex.EncodeToString(packed))
+
+	auth := []uuoskit.PermissionLevel{{uuoskit.NewName("alice"), uuoskit.NewName("active")}}
+	action, err := NewRecordAction(uuoskit.NewName("test"), auth, &record)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(hex.EncodeToString(action.Data), RecordsTable.String(), record.Size() == len(packed))
+
+	unpacked := RecordsRow{}
+	n, err := unpacked.Unpack(packed)
+	if err != nil || n != len(packed) {
+		panic(err)
+	}
+	fmt.Println(hex.EncodeToString(unpacked.Pack()), unpacked.Ext == nil, *unpacked.Nested[1])
+
+	ext := uint8(5)
+	record.Ext = &ext
+	record.Shape = NewShapeUint16(3)
+	packed = record.Pack()
+	fmt.Println(hex.EncodeToString(packed))
+	unpacked.Unpack(packed)
+	fmt.Println(*unpacked.Ext, unpacked.Shape.Value)
+
+	var list List
+	_, err = list.Unpack([]byte{0xff, 0xff, 0xff, 0xff, 0x0f})
+	fmt.Println(err)
+
+	//structs without fields take no bytes
+	empties := Empties{Items: []Empty{{}, {}, {}}, Grid: [][]uint16{{1}, {}}}
+	var unpackedEmpties Empties
+	_, err = unpackedEmpties.Unpack(empties.Pack())
+	fmt.Println(hex.EncodeToString(empties.Pack()), len(unpackedEmpties.Items), unpackedEmpties.Grid, err)
+
+	//variants with a wrong value or index return errors
+	record.Shape = Shape{Index: 1, Value: uint16(1)}
+	_, err = NewRecordAction(uuoskit.NewName("test"), auth, &record)
+	fmt.Println(err)
+	record.Shape = Shape{Index: 2}
+	_, err = record.PackE()
+	fmt.Println(err)
+}
+`
+
+// runGenerated builds the code generated from abi together with program and returns the lines printed by it
+func runGenerated(t *testing.T, abi string, program string) ([]string, bool) {
+	a := &uuoskit.ABI{}
+	err := json.Unmarshal([]byte(abi), a)
+	if err != nil {
+		panic(err)
+	}
+	code, err := generate(a, "main")
+	if err != nil {
+		panic(err)
+	}
+
+	//build in the module, where uuoskit can be imported
+	dir, err := ioutil.TempDir(".", "abigen_test")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "generated.go"), code, 0644)
+	if err != nil {
+		panic(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(program), 0644)
+	if err != nil {
+		panic(err)
+	}
+
+	out, err := exec.Command("go", "run", "./"+dir).CombinedOutput()
+	if !assert.Nil(t, err, string(out)) {
+		return nil, false
+	}
+	return strings.Split(strings.TrimSpace(string(out)), "\n"), true
+}
+
+func TestGenerate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the generated code")
+	}
+	assert := assert.New(t)
+
+	lines, ok := runGenerated(t, testAbi, testProgram)
+	if !ok {
+		return
+	}
+
+	ser := uuoskit.NewABISerializer()
+	err := ser.SetContractABI("test", []byte(testAbi))
+	if err != nil {
+		panic(err)
+	}
+	args := `{"id": 1, "owner": "alice", "balance": "1.0000 EOS", "ids": [1, 2], "memo": "hello",
+		"hash": "aa00000000000000000000000000000000000000000000000000000000000000",
+		"points": [{"x": 1, "y": 2}, {"x": 3, "y": 4}], "nested": [null, {"x": 5, "y": 6}],
+		"shape": ["point", {"x": 7, "y": 8}], "delta": -2, "data": "ff"}`
+	expected, err := ser.PackActionArgs("test", "record", args)
+	if err != nil {
+		panic(err)
+	}
+	packed := hex.EncodeToString(expected)
+	assert.Equal(packed, lines[0])
+	assert.Equal(packed+" records true", lines[1])
+	assert.Equal(packed+" true {5 6}", lines[2])
+
+	args = strings.Replace(args, `["point", {"x": 7, "y": 8}]`, `["uint16", 3]`, 1)
+	args = strings.Replace(args, `"data": "ff"`, `"data": "ff", "ext": 5`, 1)
+	expected, err = ser.PackActionArgs("test", "record", args)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(hex.EncodeToString(expected), lines[3])
+	assert.Equal("5 3", lines[4])
+	assert.Contains(lines[5], "buffer overflow")
+	assert.Equal("03"+"02"+"010100"+"00"+" 3 [[1] []] <nil>", lines[6])
+	assert.Equal("invalid shape value uint16 for point", lines[7])
+	assert.Equal("invalid shape index 2", lines[8])
+}
+
+var testClashAbi = `{
+	"version": "eosio::abi/1.1",
+	"structs": [
+		{"name": "point", "base": "", "fields": [{"name": "x", "type": "int32"}]},
+		{"name": "clash", "base": "point", "fields": [
+			{"name": "size", "type": "uint8"},
+			{"name": "pack", "type": "uint8"},
+			{"name": "unpack", "type": "uint8"},
+			{"name": "point", "type": "uint8"},
+			{"name": "a_b", "type": "uint8"},
+			{"name": "aB", "type": "uint8"}
+		]}
+	],
+	"actions": [{"name": "clash", "type": "clash", "ricardian_contract": ""}]
+}`
+
+var testClashProgram = `package main
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+func main() {
+	c := Clash{Point: Point{X: 1}, Size_: 2, Pack_: 3, Unpack_: 4, Point_: 5, AB: 6, AB_: 7}
+	fmt.Println(hex.EncodeToString(c.Pack()), c.Size())
+}
+`
+
+func TestGenerateNameClashes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the generated code")
+	}
+	assert := assert.New(t)
+
+	//fields clashing with methods, the embedded base or other fields are renamed
+	lines, ok := runGenerated(t, testClashAbi, testClashProgram)
+	if !ok {
+		return
+	}
+	ser := uuoskit.NewABISerializer()
+	err := ser.SetContractABI("test", []byte(testClashAbi))
+	if err != nil {
+		panic(err)
+	}
+	expected, err := ser.PackActionArgs("test", "clash", `{"x": 1, "size": 2, "pack": 3, "unpack": 4, "point": 5, "a_b": 6, "aB": 7}`)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal([]string{hex.EncodeToString(expected) + " 10"}, lines)
+
+	//type names can not be renamed
+	abi := &uuoskit.ABI{}
+	err = json.Unmarshal([]byte(strings.Replace(testClashAbi, `"name": "point", "base": ""`, `"name": "clash_row", "base": ""`, 1)), abi)
+	if err != nil {
+		panic(err)
+	}
+	abi.Structs[1].Base = "clash_row"
+	abi.Tables = []uuoskit.ABITable{{Name: "clash", Type: "clash"}}
+	_, err = generate(abi, "main")
+	assert.Contains(err.Error(), "struct clash_row and table clash are both generated as ClashRow")
+
+	abi.Tables = nil
+	abi.Structs[0].Name = "clashRow"
+	abi.Structs[1].Name = "clash_row"
+	abi.Structs[1].Base = ""
+	abi.Actions = nil
+	_, err = generate(abi, "main")
+	assert.Contains(err.Error(), "struct clashRow and struct clash_row are both generated as ClashRow")
+}
+
+func TestGoName(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("AccountV1", goName("account_v1"))
+	assert.Equal("StringArray", goName("string[]"))
+	assert.Equal("PointOptional", goName("point?"))
+	assert.Equal("Uint8Array4", goName("uint8[4]"))
+	assert.Equal("T1abc", goName("1abc"))
+	assert.Equal("EosioToken", goName("eosio.token"))
+}
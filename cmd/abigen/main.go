@@ -0,0 +1,62 @@
+// Command abigen generates Go types for the structs, variants and tables of a contract abi,
+// with PackE, Pack, Unpack and Size methods, and a function that creates each action, e.g.
+//
+//	abigen -abi eosio.token.abi -package token -o token.go
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/uuosio/go-uuoskit/uuoskit"
+)
+
+func main() {
+	abiFile := flag.String("abi", "", "path of the abi file")
+	pkg := flag.String("package", "", "package name of the generated code, the name of the abi file by default")
+	output := flag.String("o", "", "path of the generated file, stdout by default")
+	flag.Parse()
+
+	if *abiFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*abiFile, *pkg, *output); err != nil {
+		fmt.Fprintln(os.Stderr, "abigen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(abiFile string, pkg string, output string) error {
+	data, err := ioutil.ReadFile(abiFile)
+	if err != nil {
+		return err
+	}
+
+	abi := &uuoskit.ABI{}
+	if err := json.Unmarshal(data, abi); err != nil {
+		return err
+	}
+
+	if pkg == "" {
+		name := strings.TrimSuffix(filepath.Base(abiFile), filepath.Ext(abiFile))
+		pkg = strings.ToLower(goName(name))
+	}
+
+	code, err := generate(abi, pkg)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	return ioutil.WriteFile(output, code, 0644)
+}
@@ -26,6 +26,8 @@ import (
 	"log"
 	"math/big"
 	"runtime"
+	"strings"
+	"time"
 	"unsafe"
 
 	traceable_errors "github.com/go-errors/errors"
@@ -54,6 +56,25 @@ func renderError(err error) *C.char {
 	}
 }
 
+// recoverError renders a panic in an exported function as an error, a malformed input must not crash the process
+func recoverError(ret **C.char) {
+	if r := recover(); r != nil {
+		*ret = renderError(fmt.Errorf("panic: %v", r))
+	}
+}
+
+// recoverIndex returns -1 from an exported function that panicked
+func recoverIndex(ret *C.int64_t) {
+	if r := recover(); r != nil {
+		*ret = C.int64_t(-1)
+	}
+}
+
+// recoverPanic returns the zero value from an exported function that panicked
+func recoverPanic() {
+	recover()
+}
+
 //export init_
 func init_(malloc C.fn_malloc) {
 	C.set_malloc_fn(malloc)
@@ -76,7 +97,8 @@ func say_hello_(name *C.char) {
 }
 
 //export wallet_import_
-func wallet_import_(name *C.char, priv *C.char) *C.char {
+func wallet_import_(name *C.char, priv *C.char) (ret *C.char) {
+	defer recoverError(&ret)
 	_name := C.GoString(name)
 	_priv := C.GoString(priv)
 	err := uuoskit.GetWallet().Import(_name, _priv)
@@ -89,6 +111,7 @@ func wallet_import_(name *C.char, priv *C.char) *C.char {
 
 //export wallet_remove_
 func wallet_remove_(name *C.char, pubKey *C.char) C.bool {
+	defer recoverPanic()
 	_name := C.GoString(name)
 	_pubKey := C.GoString(pubKey)
 	ret := uuoskit.GetWallet().Remove(_name, _pubKey)
@@ -96,13 +119,15 @@ func wallet_remove_(name *C.char, pubKey *C.char) C.bool {
 }
 
 //export wallet_get_public_keys_
-func wallet_get_public_keys_() *C.char {
+func wallet_get_public_keys_() (ret *C.char) {
+	defer recoverError(&ret)
 	keys := uuoskit.GetWallet().GetPublicKeys()
 	return renderData(keys)
 }
 
 //export wallet_sign_digest_
-func wallet_sign_digest_(digest *C.char, pubKey *C.char) *C.char {
+func wallet_sign_digest_(digest *C.char, pubKey *C.char) (ret *C.char) {
+	defer recoverError(&ret)
 	_pubKey := C.GoString(pubKey)
 	// log.Println("++++++++wallet_sign_digest_:", C.GoString(digest))
 	_digest, err := hex.DecodeString(C.GoString(digest))
@@ -117,6 +142,110 @@ func wallet_sign_digest_(digest *C.char, pubKey *C.char) *C.char {
 	return renderData(sign.String())
 }
 
+//export wallet_set_dir_
+func wallet_set_dir_(dir *C.char) (ret *C.char) {
+	defer recoverError(&ret)
+	uuoskit.GetWallet().SetWalletDir(C.GoString(dir))
+	return renderData("ok")
+}
+
+//export wallet_create_
+func wallet_create_(name *C.char, password *C.char) (ret *C.char) {
+	defer recoverError(&ret)
+	_, err := uuoskit.GetWallet().Create(C.GoString(name), C.GoString(password))
+	if err != nil {
+		return renderError(err)
+	}
+	return renderData("ok")
+}
+
+//export wallet_open_
+func wallet_open_(name *C.char) (ret *C.char) {
+	defer recoverError(&ret)
+	_, err := uuoskit.GetWallet().Open(C.GoString(name))
+	if err != nil {
+		return renderError(err)
+	}
+	return renderData("ok")
+}
+
+//export wallet_lock_
+func wallet_lock_(name *C.char) (ret *C.char) {
+	defer recoverError(&ret)
+	err := uuoskit.GetWallet().Lock(C.GoString(name))
+	if err != nil {
+		return renderError(err)
+	}
+	return renderData("ok")
+}
+
+//export wallet_lock_all_
+func wallet_lock_all_() (ret *C.char) {
+	defer recoverError(&ret)
+	err := uuoskit.GetWallet().LockAll()
+	if err != nil {
+		return renderError(err)
+	}
+	return renderData("ok")
+}
+
+//export wallet_unlock_
+func wallet_unlock_(name *C.char, password *C.char) (ret *C.char) {
+	defer recoverError(&ret)
+	err := uuoskit.GetWallet().Unlock(C.GoString(name), C.GoString(password))
+	if err != nil {
+		return renderError(err)
+	}
+	return renderData("ok")
+}
+
+//export wallet_is_locked_
+func wallet_is_locked_(name *C.char) (ret *C.char) {
+	defer recoverError(&ret)
+	w, err := uuoskit.GetWallet().GetWallet(C.GoString(name))
+	if err != nil {
+		return renderError(err)
+	}
+	return renderData(w.IsLocked())
+}
+
+//export wallet_set_timeout_
+func wallet_set_timeout_(seconds C.int64_t) {
+	uuoskit.GetWallet().SetLockTimeout(time.Duration(seconds) * time.Second)
+}
+
+//export wallet_list_
+func wallet_list_() (ret *C.char) {
+	defer recoverError(&ret)
+	return renderData(uuoskit.GetWallet().List())
+}
+
+//export wallet_list_files_
+func wallet_list_files_() (ret *C.char) {
+	defer recoverError(&ret)
+	names, err := uuoskit.GetWallet().ListWalletFiles()
+	if err != nil {
+		return renderError(err)
+	}
+	return renderData(names)
+}
+
+//export wallet_select_
+func wallet_select_(names *C.char) (ret *C.char) {
+	defer recoverError(&ret)
+	_names := []string{}
+	err := json.Unmarshal([]byte(C.GoString(names)), &_names)
+	if err != nil {
+		return renderError(err)
+	}
+
+	err = uuoskit.GetWallet().Select(_names...)
+	if err != nil {
+		return renderError(err)
+	}
+	return renderData("ok")
+}
+
 var gChainContexts []*uuoskit.ChainContext
 
 //export new_chain_context_
@@ -141,7 +270,8 @@ func new_chain_context_() C.int64_t {
 }
 
 //export chain_context_free_
-func chain_context_free_(_index C.int64_t) *C.char {
+func chain_context_free_(_index C.int64_t) (ret *C.char) {
+	defer recoverError(&ret)
 	index := int(_index)
 	if index < 0 || index >= len(gChainContexts) {
 		return renderError(fmt.Errorf("bad chain index %d", index))
@@ -191,7 +321,8 @@ func addPackedTx(ctx *uuoskit.ChainContext, packedTx *uuoskit.PackedTransaction)
 }
 
 //export transaction_new_
-func transaction_new_(chainIndex C.int64_t, expiration C.int64_t, refBlock *C.char, chainId *C.char) C.int64_t {
+func transaction_new_(chainIndex C.int64_t, expiration C.int64_t, refBlock *C.char, chainId *C.char) (ret C.int64_t) {
+	defer recoverIndex(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return C.int64_t(-1)
@@ -206,7 +337,8 @@ func transaction_new_(chainIndex C.int64_t, expiration C.int64_t, refBlock *C.ch
 }
 
 //export transaction_from_json_
-func transaction_from_json_(chainIndex C.int64_t, tx *C.char, chainId *C.char) *C.char {
+func transaction_from_json_(chainIndex C.int64_t, tx *C.char, chainId *C.char) (ret *C.char) {
+	defer recoverError(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return renderError(err)
@@ -223,7 +355,8 @@ func transaction_from_json_(chainIndex C.int64_t, tx *C.char, chainId *C.char) *
 }
 
 //export transaction_free_
-func transaction_free_(chainIndex C.int64_t, _index C.int64_t) *C.char {
+func transaction_free_(chainIndex C.int64_t, _index C.int64_t) (ret *C.char) {
+	defer recoverError(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return renderError(err)
@@ -238,7 +371,8 @@ func transaction_free_(chainIndex C.int64_t, _index C.int64_t) *C.char {
 }
 
 //export transaction_set_chain_id_
-func transaction_set_chain_id_(chainIndex C.int64_t, _index C.int64_t, chainId *C.char) *C.char {
+func transaction_set_chain_id_(chainIndex C.int64_t, _index C.int64_t, chainId *C.char) (ret *C.char) {
+	defer recoverError(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return renderError(err)
@@ -256,7 +390,8 @@ func transaction_set_chain_id_(chainIndex C.int64_t, _index C.int64_t, chainId *
 }
 
 //export transaction_add_action_
-func transaction_add_action_(chainIndex C.int64_t, idx C.int64_t, account *C.char, name *C.char, data *C.char, permissions *C.char) *C.char {
+func transaction_add_action_(chainIndex C.int64_t, idx C.int64_t, account *C.char, name *C.char, data *C.char, permissions *C.char) (ret *C.char) {
+	defer recoverError(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return renderError(err)
@@ -301,8 +436,67 @@ func transaction_add_action_(chainIndex C.int64_t, idx C.int64_t, account *C.cha
 	return renderData("ok")
 }
 
+//export transaction_add_context_free_action_
+func transaction_add_context_free_action_(chainIndex C.int64_t, idx C.int64_t, account *C.char, name *C.char, data *C.char) (ret *C.char) {
+	defer recoverError(&ret)
+	ctx, err := getChainContext(int(chainIndex))
+	if err != nil {
+		return renderError(err)
+	}
+
+	if err := validateIndex(ctx.PackedTxs, idx); err != nil {
+		return renderError(err)
+	}
+
+	_account := C.GoString(account)
+	_name := C.GoString(name)
+	_data := C.GoString(data)
+
+	var __data []byte
+	__data, err = hex.DecodeString(_data)
+	if err != nil {
+		__data, err = ctx.ABISerializer.PackActionArgs(_account, _name, _data)
+		if err != nil {
+			return renderError(err)
+		}
+	}
+
+	action := uuoskit.NewAction(uuoskit.NewName(_account), uuoskit.NewName(_name))
+	action.SetData(__data)
+	err = ctx.PackedTxs[idx].AddContextFreeAction(action)
+	if err != nil {
+		return renderError(err)
+	}
+	return renderData("ok")
+}
+
+//export transaction_add_context_free_data_
+func transaction_add_context_free_data_(chainIndex C.int64_t, idx C.int64_t, data *C.char) (ret *C.char) {
+	defer recoverError(&ret)
+	ctx, err := getChainContext(int(chainIndex))
+	if err != nil {
+		return renderError(err)
+	}
+
+	if err := validateIndex(ctx.PackedTxs, idx); err != nil {
+		return renderError(err)
+	}
+
+	_data, err := hex.DecodeString(C.GoString(data))
+	if err != nil {
+		return renderError(err)
+	}
+
+	err = ctx.PackedTxs[idx].AddContextFreeData(_data)
+	if err != nil {
+		return renderError(err)
+	}
+	return renderData("ok")
+}
+
 //export transaction_sign_
-func transaction_sign_(chainIndex C.int64_t, idx C.int64_t, pub *C.char) *C.char {
+func transaction_sign_(chainIndex C.int64_t, idx C.int64_t, pub *C.char) (ret *C.char) {
+	defer recoverError(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return renderError(err)
@@ -321,7 +515,8 @@ func transaction_sign_(chainIndex C.int64_t, idx C.int64_t, pub *C.char) *C.char
 }
 
 //export transaction_digest_
-func transaction_digest_(chainIndex C.int64_t, idx C.int64_t, chainId *C.char) *C.char {
+func transaction_digest_(chainIndex C.int64_t, idx C.int64_t, chainId *C.char) (ret *C.char) {
+	defer recoverError(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return renderError(err)
@@ -340,7 +535,8 @@ func transaction_digest_(chainIndex C.int64_t, idx C.int64_t, chainId *C.char) *
 }
 
 //export transaction_sign_by_private_key_
-func transaction_sign_by_private_key_(chainIndex C.int64_t, idx C.int64_t, priv *C.char) *C.char {
+func transaction_sign_by_private_key_(chainIndex C.int64_t, idx C.int64_t, priv *C.char) (ret *C.char) {
+	defer recoverError(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return renderError(err)
@@ -358,7 +554,8 @@ func transaction_sign_by_private_key_(chainIndex C.int64_t, idx C.int64_t, priv
 }
 
 //export transaction_pack_
-func transaction_pack_(chainIndex C.int64_t, idx C.int64_t, compress C.int) *C.char {
+func transaction_pack_(chainIndex C.int64_t, idx C.int64_t, compress C.int) (ret *C.char) {
+	defer recoverError(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return renderError(err)
@@ -378,7 +575,8 @@ func transaction_pack_(chainIndex C.int64_t, idx C.int64_t, compress C.int) *C.c
 }
 
 //export transaction_marshal_
-func transaction_marshal_(chainIndex C.int64_t, idx C.int64_t) *C.char {
+func transaction_marshal_(chainIndex C.int64_t, idx C.int64_t) (ret *C.char) {
+	defer recoverError(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return renderError(err)
@@ -393,14 +591,30 @@ func transaction_marshal_(chainIndex C.int64_t, idx C.int64_t) *C.char {
 }
 
 //export transaction_unpack_
-func transaction_unpack_(data *C.char) *C.char {
+func transaction_unpack_(data *C.char) (ret *C.char) {
+	defer recoverError(&ret)
+	_data := C.GoString(data)
+	if strings.HasPrefix(strings.TrimSpace(_data), "{") {
+		packedTx, err := uuoskit.UnpackPackedTransaction(_data)
+		if err != nil {
+			return renderError(err)
+		}
+		js, err := json.Marshal(packedTx.GetSignedTransaction())
+		if err != nil {
+			return renderError(err)
+		}
+		return renderData(string(js))
+	}
+
 	t := uuoskit.Transaction{}
-	_data, err := hex.DecodeString(C.GoString(data))
+	__data, err := hex.DecodeString(_data)
 	if err != nil {
 		return renderError(err)
 	}
 
-	t.Unpack([]byte(_data))
+	if _, err := t.Unpack(__data); err != nil {
+		return renderError(err)
+	}
 	js, err := json.Marshal(t)
 	if err != nil {
 		return renderError(err)
@@ -408,8 +622,50 @@ func transaction_unpack_(data *C.char) *C.char {
 	return renderData(string(js))
 }
 
+//export transaction_verify_
+func transaction_verify_(tx *C.char, chainId *C.char) (ret *C.char) {
+	defer recoverError(&ret)
+	packedTx, err := uuoskit.NewPackedTransactionFromString(C.GoString(tx))
+	if err != nil {
+		return renderError(err)
+	}
+
+	err = packedTx.SetChainId(C.GoString(chainId))
+	if err != nil {
+		return renderError(err)
+	}
+
+	result, err := packedTx.Verify()
+	if err != nil {
+		return renderError(err)
+	}
+	return renderData(result)
+}
+
+//export transaction_merge_signatures_
+func transaction_merge_signatures_(packedTxs *C.char) (ret *C.char) {
+	defer recoverError(&ret)
+	txs := []json.RawMessage{}
+	err := json.Unmarshal([]byte(C.GoString(packedTxs)), &txs)
+	if err != nil {
+		return renderError(err)
+	}
+
+	_txs := make([]string, 0, len(txs))
+	for _, tx := range txs {
+		_txs = append(_txs, string(tx))
+	}
+
+	merged, err := uuoskit.MergePackedTransactions(_txs...)
+	if err != nil {
+		return renderError(err)
+	}
+	return renderData(merged.Pack(merged.Compression == "zlib"))
+}
+
 //export abiserializer_set_contract_abi_
-func abiserializer_set_contract_abi_(chainIndex C.int64_t, account *C.char, abi *C.char, length C.int) *C.char {
+func abiserializer_set_contract_abi_(chainIndex C.int64_t, account *C.char, abi *C.char, length C.int) (ret *C.char) {
+	defer recoverError(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return renderError(err)
@@ -425,7 +681,8 @@ func abiserializer_set_contract_abi_(chainIndex C.int64_t, account *C.char, abi
 }
 
 //export abiserializer_pack_action_args_
-func abiserializer_pack_action_args_(chainIndex C.int64_t, contractName *C.char, actionName *C.char, args *C.char, args_len C.int) *C.char {
+func abiserializer_pack_action_args_(chainIndex C.int64_t, contractName *C.char, actionName *C.char, args *C.char, args_len C.int) (ret *C.char) {
+	defer recoverError(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return renderError(err)
@@ -442,7 +699,8 @@ func abiserializer_pack_action_args_(chainIndex C.int64_t, contractName *C.char,
 }
 
 //export abiserializer_unpack_action_args_
-func abiserializer_unpack_action_args_(chainIndex C.int64_t, contractName *C.char, actionName *C.char, args *C.char) *C.char {
+func abiserializer_unpack_action_args_(chainIndex C.int64_t, contractName *C.char, actionName *C.char, args *C.char) (ret *C.char) {
+	defer recoverError(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return renderError(err)
@@ -463,7 +721,8 @@ func abiserializer_unpack_action_args_(chainIndex C.int64_t, contractName *C.cha
 }
 
 //export abiserializer_pack_abi_type_
-func abiserializer_pack_abi_type_(chainIndex C.int64_t, contractName *C.char, actionName *C.char, args *C.char, args_len C.int) *C.char {
+func abiserializer_pack_abi_type_(chainIndex C.int64_t, contractName *C.char, actionName *C.char, args *C.char, args_len C.int) (ret *C.char) {
+	defer recoverError(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return renderError(err)
@@ -480,7 +739,8 @@ func abiserializer_pack_abi_type_(chainIndex C.int64_t, contractName *C.char, ac
 }
 
 //export abiserializer_unpack_abi_type_
-func abiserializer_unpack_abi_type_(chainIndex C.int64_t, contractName *C.char, actionName *C.char, args *C.char) *C.char {
+func abiserializer_unpack_abi_type_(chainIndex C.int64_t, contractName *C.char, actionName *C.char, args *C.char) (ret *C.char) {
+	defer recoverError(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return renderError(err)
@@ -500,8 +760,31 @@ func abiserializer_unpack_abi_type_(chainIndex C.int64_t, contractName *C.char,
 	return renderData(string(result))
 }
 
+//export abiserializer_unpack_action_result_
+func abiserializer_unpack_action_result_(chainIndex C.int64_t, contractName *C.char, actionName *C.char, result *C.char) (ret *C.char) {
+	defer recoverError(&ret)
+	ctx, err := getChainContext(int(chainIndex))
+	if err != nil {
+		return renderError(err)
+	}
+
+	_contractName := C.GoString(contractName)
+	_actionName := C.GoString(actionName)
+	_result := C.GoString(result)
+	__result, err := hex.DecodeString(_result)
+	if err != nil {
+		return renderError(err)
+	}
+	value, err := ctx.ABISerializer.UnpackActionResult(_contractName, _actionName, __result)
+	if err != nil {
+		return renderError(err)
+	}
+	return renderData(string(value))
+}
+
 //export abiserializer_is_abi_cached_
 func abiserializer_is_abi_cached_(chainIndex C.int64_t, contractName *C.char) C.int {
+	defer recoverPanic()
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return 0
@@ -516,13 +799,26 @@ func abiserializer_is_abi_cached_(chainIndex C.int64_t, contractName *C.char) C.
 	}
 }
 
+//export abiserializer_set_strict_mode_
+func abiserializer_set_strict_mode_(chainIndex C.int64_t, strict C.int) (ret *C.char) {
+	defer recoverError(&ret)
+	ctx, err := getChainContext(int(chainIndex))
+	if err != nil {
+		return renderError(err)
+	}
+	ctx.ABISerializer.SetStrictMode(strict != 0)
+	return renderData("ok")
+}
+
 //export s2n_
 func s2n_(s *C.char) C.uint64_t {
+	defer recoverPanic()
 	return C.uint64_t(uuoskit.S2N(C.GoString(s)))
 }
 
 //export n2s_
-func n2s_(n C.uint64_t) *C.char {
+func n2s_(n C.uint64_t) (ret *C.char) {
+	defer recoverError(&ret)
 	return CString(uuoskit.N2S(uint64(n)))
 }
 
@@ -530,12 +826,16 @@ func n2s_(n C.uint64_t) *C.char {
 //
 //export sym2n_
 func sym2n_(str_symbol *C.char, precision C.uint64_t) C.uint64_t {
-	v := uuoskit.NewSymbol(C.GoString(str_symbol), int(uint64(precision))).Value
-	return C.uint64_t(v)
+	sym, err := uuoskit.NewSymbolE(C.GoString(str_symbol), int(uint64(precision)))
+	if err != nil {
+		return 0
+	}
+	return C.uint64_t(sym.Value)
 }
 
 //export abiserializer_pack_abi_
-func abiserializer_pack_abi_(chainIndex C.int64_t, str_abi *C.char) *C.char {
+func abiserializer_pack_abi_(chainIndex C.int64_t, str_abi *C.char) (ret *C.char) {
+	defer recoverError(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return renderError(err)
@@ -550,7 +850,8 @@ func abiserializer_pack_abi_(chainIndex C.int64_t, str_abi *C.char) *C.char {
 }
 
 //export abiserializer_unpack_abi_
-func abiserializer_unpack_abi_(chainIndex C.int64_t, abi *C.char, length C.int) *C.char {
+func abiserializer_unpack_abi_(chainIndex C.int64_t, abi *C.char, length C.int) (ret *C.char) {
+	defer recoverError(&ret)
 	ctx, err := getChainContext(int(chainIndex))
 	if err != nil {
 		return renderError(err)
@@ -565,10 +866,11 @@ func abiserializer_unpack_abi_(chainIndex C.int64_t, abi *C.char, length C.int)
 }
 
 //export crypto_sign_digest_
-func crypto_sign_digest_(digest *C.char, privateKey *C.char) *C.char {
+func crypto_sign_digest_(digest *C.char, privateKey *C.char) (ret *C.char) {
+	defer recoverError(&ret)
 	// log.Println(C.GoString(digest), C.GoString(privateKey))
 
-	_privateKey, err := secp256k1.NewPrivateKeyFromBase58(C.GoString(privateKey))
+	_privateKey, err := uuoskit.NewPrivateKeyFromString(C.GoString(privateKey))
 	if err != nil {
 		return renderError(err)
 	}
@@ -586,8 +888,9 @@ func crypto_sign_digest_(digest *C.char, privateKey *C.char) *C.char {
 }
 
 //export crypto_get_public_key_
-func crypto_get_public_key_(privateKey *C.char, eosPub C.int) *C.char {
-	_privateKey, err := secp256k1.NewPrivateKeyFromBase58(C.GoString(privateKey))
+func crypto_get_public_key_(privateKey *C.char, eosPub C.int) (ret *C.char) {
+	defer recoverError(&ret)
+	_privateKey, err := uuoskit.NewPrivateKeyFromString(C.GoString(privateKey))
 	if err != nil {
 		return renderError(err)
 	}
@@ -602,18 +905,19 @@ func crypto_get_public_key_(privateKey *C.char, eosPub C.int) *C.char {
 }
 
 //export crypto_recover_key_
-func crypto_recover_key_(digest *C.char, signature *C.char, format C.int) *C.char {
+func crypto_recover_key_(digest *C.char, signature *C.char, format C.int) (ret *C.char) {
+	defer recoverError(&ret)
 	_digest, err := hex.DecodeString(C.GoString(digest))
 	if err != nil {
 		return renderError(err)
 	}
 
-	_signature, err := secp256k1.NewSignatureFromBase58(C.GoString(signature))
+	_signature, err := uuoskit.NewSignatureFromString(C.GoString(signature))
 	if err != nil {
 		return renderError(err)
 	}
 
-	pub, err := secp256k1.Recover(_digest, _signature)
+	pub, err := uuoskit.RecoverPublicKey(_digest, _signature)
 	if err != nil {
 		return renderError(err)
 	}
@@ -626,9 +930,10 @@ func crypto_recover_key_(digest *C.char, signature *C.char, format C.int) *C.cha
 }
 
 //export crypto_create_key_
-func crypto_create_key_(oldPubKeyFormat C.bool) *C.char {
-	ret := CreateKey(bool(oldPubKeyFormat))
-	return renderData(ret)
+func crypto_create_key_(oldPubKeyFormat C.bool) (ret *C.char) {
+	defer recoverError(&ret)
+	key := CreateKey(bool(oldPubKeyFormat))
+	return renderData(key)
 }
 
 func CreateKey(oldPubKeyFormat bool) map[string]string {
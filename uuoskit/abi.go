@@ -11,7 +11,6 @@ import (
 	"time"
 
 	"github.com/iancoleman/orderedmap"
-	secp256k1 "github.com/uuosio/go-secp256k1"
 )
 
 type ABITable struct {
@@ -76,16 +75,43 @@ type AbiExtendedAsset struct {
 	Contract string `json:"contract"`
 }
 
+// ABIActionResult is the type of the value returned by an action, since abi 1.2
+type ABIActionResult struct {
+	Name       string `json:"name"`
+	ResultType string `json:"result_type"`
+}
+
+// ABIKvPrimaryIndex is the primary index of a kv table, since abi 1.2
+type ABIKvPrimaryIndex struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type ABIKvSecondaryIndex struct {
+	Type string `json:"type"`
+}
+
+// ABIKvTable is a table of the kv database of EOSIO 2.1, since abi 1.2
+type ABIKvTable struct {
+	Type             string                         `json:"type"`
+	PrimaryIndex     ABIKvPrimaryIndex              `json:"primary_index"`
+	SecondaryIndices map[string]ABIKvSecondaryIndex `json:"secondary_indices"`
+}
+
 type ABI struct {
-	Version          string         `json:"version"`
-	Types            []ABIType      `json:"types"`
-	Structs          []ABIStruct    `json:"structs"`
-	Actions          []ABIAction    `json:"actions"`
-	Tables           []ABITable     `json:"tables"`
-	RicardianClauses []ClausePair   `json:"ricardian_clauses"`
-	ErrorMessages    []ErrorMessage `json:"error_messages"`
-	AbiExtensions    []AbiExtension `json:"abi_extensions"`
-	Variants         []VariantDef   `json:"variants"`
+	Version          string                `json:"version"`
+	Types            []ABIType             `json:"types"`
+	Structs          []ABIStruct           `json:"structs"`
+	Actions          []ABIAction           `json:"actions"`
+	Tables           []ABITable            `json:"tables"`
+	RicardianClauses []ClausePair          `json:"ricardian_clauses"`
+	ErrorMessages    []ErrorMessage        `json:"error_messages"`
+	AbiExtensions    []AbiExtension        `json:"abi_extensions"`
+	Variants         []VariantDef          `json:"variants"`
+	ActionResults    []ABIActionResult     `json:"action_results,omitempty"`
+	KvTables         map[string]ABIKvTable `json:"kv_tables,omitempty"`
+	cache            *abiCache
+	strict           bool
 }
 
 func (t *ABI) PackAbiType(abiType string, args string) ([]byte, error) {
@@ -392,24 +418,22 @@ func (t *ABI) ParseAbiStringValue(enc *Encoder, typ string, v string) error {
 		if !ok {
 			return newErrorf("invalid public_key value: %s", v)
 		}
-		pub, err := secp256k1.NewPublicKeyFromBase58(v)
+		pub, err := NewPublicKeyFromString(v)
 		if err != nil {
-			return newError(err)
+			return err
 		}
-		enc.WriteBytes([]byte{0})
-		enc.WriteBytes(pub.Data[:])
+		enc.WriteBytes(pub.Pack())
 		break
 	case "signature":
 		v, ok := StripString(v)
 		if !ok {
 			return newErrorf("invalid signature value: %s", v)
 		}
-		sig, err := secp256k1.NewSignatureFromBase58(v)
+		sig, err := NewSignatureFromString(v)
 		if err != nil {
-			return newError(err)
+			return err
 		}
-		enc.WriteBytes([]byte{0})
-		enc.WriteBytes(sig.Data[:])
+		enc.WriteBytes(sig.Pack())
 	case "symbol":
 		v, ok := StripString(v)
 		if !ok {
@@ -630,22 +654,20 @@ func (t *ABI) unpackAbiStructField(dec *Decoder, typ string) (interface{}, error
 		}
 		return hex.EncodeToString(v), nil
 	case "public_key":
-		v := make([]byte, 34)
-		err := dec.Read(v)
+		pub := PublicKey{}
+		n, err := pub.Unpack(dec.Remains())
 		if err != nil {
 			return nil, newError(err)
 		}
-		pub := secp256k1.PublicKey{}
-		copy(pub.Data[:], v[1:])
+		dec.incPos(n)
 		return pub.StringEOS(), nil
 	case "signature":
-		v := make([]byte, 66)
-		err := dec.Read(v)
+		sig := Signature{}
+		n, err := sig.Unpack(dec.Remains())
 		if err != nil {
 			return nil, newError(err)
 		}
-		sig := secp256k1.Signature{}
-		copy(sig.Data[:], v[1:])
+		dec.incPos(n)
 		return sig.String(), nil
 	case "symbol":
 		buf := make([]byte, 8)
@@ -700,11 +722,110 @@ func (t *ABI) unpackAbiStructField(dec *Decoder, typ string) (interface{}, error
 	}
 }
 
+const (
+	abiTypeNone = iota
+	abiTypeOptional
+	abiTypeArray
+	abiTypeFixedArray
+)
+
+// parseAbiType splits the outermost modifier off typ, e.g. "uint64[2]?" is an optional of "uint64[2]",
+// which is a fixed size array of 2 "uint64". size is only set for fixed size arrays
+func parseAbiType(typ string) (kind int, elem string, size int) {
+	if strings.HasSuffix(typ, "?") {
+		return abiTypeOptional, typ[:len(typ)-1], 0
+	}
+	if !strings.HasSuffix(typ, "]") {
+		return abiTypeNone, typ, 0
+	}
+
+	pos := strings.LastIndex(typ, "[")
+	if pos <= 0 {
+		return abiTypeNone, typ, 0
+	}
+	if pos == len(typ)-2 {
+		return abiTypeArray, typ[:pos], 0
+	}
+
+	n, err := strconv.ParseUint(typ[pos+1:len(typ)-1], 10, 32)
+	if err != nil || n == 0 {
+		return abiTypeNone, typ, 0
+	}
+	return abiTypeFixedArray, typ[:pos], int(n)
+}
+
+// unpackArrayCount returns the number of elements of an array or fixed size array to unpack,
+// the count comes from the packed data or the abi
+func unpackArrayCount(dec *Decoder, kind int, size int) (int, error) {
+	if kind != abiTypeArray {
+		return size, nil
+	}
+	count, err := dec.UnpackLength()
+	if err != nil {
+		return 0, newError(err)
+	}
+	return count, nil
+}
+
+// arrayCapacity bounds the capacity allocated for count elements by the remaining bytes. count can not
+// be rejected by them, elements like structs without fields take no bytes
+func arrayCapacity(dec *Decoder, count int) int {
+	if remains := len(dec.Remains()); count > remains {
+		return remains
+	}
+	return count
+}
+
+// unpackModifiedValue unpacks typ if it is an optional, an array or a fixed size array, the elements
+// are unpacked with unpackElem. ok is false for other types
+func unpackModifiedValue(dec *Decoder, typ string, unpackElem func(dec *Decoder, typ string) (interface{}, error)) (value interface{}, ok bool, err error) {
+	kind, elem, size := parseAbiType(typ)
+	switch kind {
+	case abiTypeOptional:
+		v, err := dec.UnpackBool()
+		if err != nil {
+			return nil, true, newError(err)
+		}
+		if !v {
+			return nil, true, nil
+		}
+		value, err = unpackElem(dec, elem)
+		return value, true, err
+	case abiTypeArray, abiTypeFixedArray:
+		count, err := unpackArrayCount(dec, kind, size)
+		if err != nil {
+			return nil, true, err
+		}
+
+		arr := make([]interface{}, 0, arrayCapacity(dec, count))
+		for i := 0; i < count; i++ {
+			v, err := unpackElem(dec, elem)
+			if err != nil {
+				return nil, true, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, true, nil
+	}
+	return nil, false, nil
+}
+
+// PackArrayAbiValue packs value as typ, which is an array or a fixed size array. Fixed size arrays have no length prefix
 func (t *ABI) PackArrayAbiValue(enc *Encoder, typ string, value []JsonValue) error {
-	enc.PackVarUint32(uint32(len(value)))
+	kind, elem, size := parseAbiType(typ)
+	switch kind {
+	case abiTypeArray:
+		enc.PackVarUint32(uint32(len(value)))
+	case abiTypeFixedArray:
+		if len(value) != size {
+			return newErrorf("%s requires %d values, got %d", typ, size, len(value))
+		}
+	default:
+		return newErrorf("%s is not an array type", typ)
+	}
+
 	for _, v := range value {
-		_typ := strings.TrimSuffix(typ, "[]")
-		err := t.PackAbiValue(enc, _typ, v)
+		err := t.PackAbiValue(enc, elem, v)
 		if err != nil {
 			return newError(err)
 		}
@@ -712,39 +833,41 @@ func (t *ABI) PackArrayAbiValue(enc *Encoder, typ string, value []JsonValue) err
 	return nil
 }
 
+// GetBaseABIType returns the type at the end of the typedef chain of typ
 func (t *ABI) GetBaseABIType(typ string) (string, bool) {
-	for _, v := range t.Types {
-		if v.NewTypeName == typ {
-			return v.Type, true
-		}
-	}
-	return "", false
+	v, ok := t.getCache().typedefs[typ]
+	return v, ok
 }
 
 func (t *ABI) GetVariantType(typ string) (*VariantDef, bool) {
-	for i := range t.Variants {
-		v := &t.Variants[i]
-		if v.Name == typ {
-			return v, true
-		}
-	}
-	return nil, false
+	cache := t.getCache()
+	v, ok := cache.variants[cache.resolve(typ)]
+	return v, ok
 }
 
-func (t *ABI) PackAbiStruct(enc *Encoder, structName string, m map[string]JsonValue) error {
-	abiStruct := t.GetAbiStruct(structName)
-	if abiStruct == nil {
-		return newErrorf("abi struct %s not found", structName)
+// getStructFields returns the fields of structName, including the fields of its bases
+func (t *ABI) getStructFields(structName string) ([]ABIStructField, error) {
+	cache := t.getCache()
+	name := cache.resolve(structName)
+	abiStruct, ok := cache.structs[name]
+	if !ok {
+		return nil, newErrorf("abi struct %s not found", structName)
 	}
 
-	if abiStruct.Base != "" {
-		err := t.PackAbiStruct(enc, abiStruct.Base, m)
-		if err != nil {
-			return newError(err)
-		}
+	fields, ok := cache.fields[name]
+	if !ok {
+		return nil, newErrorf("abi struct %s not found", abiStruct.Base)
+	}
+	return fields, nil
+}
+
+func (t *ABI) PackAbiStruct(enc *Encoder, structName string, m map[string]JsonValue) error {
+	fields, err := t.getStructFields(structName)
+	if err != nil {
+		return err
 	}
 
-	for _, v := range abiStruct.Fields {
+	for _, v := range fields {
 		typ := v.Type
 		name := v.Name
 		abiValue, ok := m[name]
@@ -757,39 +880,8 @@ func (t *ABI) PackAbiStruct(enc *Encoder, structName string, m map[string]JsonVa
 			return newErrorf("missing field %s", name)
 		}
 
-		if strings.HasSuffix(typ, "$") {
-			typ = strings.TrimSuffix(typ, "$")
-		} else if strings.HasSuffix(typ, "?") {
-			typ = strings.TrimSuffix(typ, "?")
-			if value, ok := abiValue.GetValue().(string); ok {
-				if value == "null" {
-					enc.PackBool(false)
-					continue
-				} else {
-					enc.PackBool(true)
-				}
-			}
-		}
-
-		for i := range t.Types {
-			v := &t.Types[i]
-			if v.NewTypeName == typ {
-				typ = v.Type
-				break
-			}
-		}
-
+		typ = strings.TrimSuffix(typ, "$")
 		err := t.PackAbiValue(enc, typ, abiValue)
-		if err == nil {
-			continue
-		}
-
-		typ, ok = t.GetBaseABIType(typ)
-		if !ok {
-			return newError(err)
-		}
-
-		err = t.PackAbiValue(enc, typ, abiValue)
 		if err != nil {
 			return newError(err)
 		}
@@ -798,19 +890,12 @@ func (t *ABI) PackAbiStruct(enc *Encoder, structName string, m map[string]JsonVa
 }
 
 func (t *ABI) UnpackAbiStruct(dec *Decoder, structName string, result *orderedmap.OrderedMap) error {
-	abiStruct := t.GetAbiStruct(structName)
-	if abiStruct == nil {
-		return newErrorf("abi struct %s not found", structName)
-	}
-
-	if abiStruct.Base != "" {
-		err := t.UnpackAbiStruct(dec, abiStruct.Base, result)
-		if err != nil {
-			return newError(err)
-		}
+	fields, err := t.getStructFields(structName)
+	if err != nil {
+		return err
 	}
 
-	err := t.unpackAbiStructFields(dec, abiStruct.Fields, result)
+	err = t.unpackAbiStructFields(dec, fields, result)
 	if err != nil {
 		return err
 	}
@@ -820,159 +905,79 @@ func (t *ABI) UnpackAbiStruct(dec *Decoder, structName string, result *orderedma
 func (t *ABI) unpackAbiStructFields(dec *Decoder, fields []ABIStructField, result *orderedmap.OrderedMap) error {
 	for _, v := range fields {
 		typ := v.Type
-		name := v.Name
-
-		//handle optional
-		if strings.HasSuffix(typ, "?") {
-			v, err := dec.UnpackBool()
-			if err != nil {
-				return newError(err)
-			}
-			if !v {
-				result.Set(name, nil)
-				continue
-			}
-			typ = strings.TrimRight(typ, "?")
-		} else if strings.HasSuffix(typ, "$") { //handle binary_extension
+		//handle binary_extension
+		if strings.HasSuffix(typ, "$") {
 			if dec.IsEnd() {
 				return nil
 			}
-			typ = strings.TrimRight(typ, "$")
-		}
-
-		//try to find base type
-		if baseName, ok := t.GetBaseName(typ); ok {
-			typ = baseName
-		}
-		//try to unpack inner abi type
-		if _, ok := gBaseTypes[typ]; ok {
-			v, err := t.unpackAbiStructField(dec, typ)
-			if err != nil {
-				return err
-			}
-			result.Set(name, v)
-			continue
-		}
-
-		//try to unpack Abi struct
-		subStruct := t.GetAbiStruct(typ)
-		if subStruct != nil {
-			subResult := orderedmap.New()
-			result.Set(name, subResult)
-			err := t.UnpackAbiStruct(dec, typ, subResult)
-			if err != nil {
-				return newError(err)
-			}
-			continue
+			typ = strings.TrimSuffix(typ, "$")
 		}
-		//try to unpack variant type
-		if v, ok := t.GetVariantType(typ); ok {
-			index, err := dec.UnpackUint8()
-			if err != nil {
-				return err
-			}
 
-			if int(index) >= len(v.Types) {
-				return newErrorf("invalid variant index %d", index)
-			}
-			tp := v.Types[int(index)]
-			arr := make([]interface{}, 0)
-			arr = append(arr, tp)
-			value, err := t.unpackAbiStructField(dec, tp)
-			if err != nil {
-				return err
-			}
-			arr = append(arr, value)
-			result.Set(name, arr)
-			continue
-		}
-		//try to unpack array
-		if !strings.HasSuffix(typ, "[]") {
-			return newErrorf("unknown type %s", typ)
-		}
-		typ = strings.TrimSuffix(typ, "[]")
-		arr := make([]interface{}, 0)
-		count, err := dec.UnpackLength()
+		value, err := t.unpackAbiValue(dec, typ)
 		if err != nil {
-			return newError(err)
-		}
-
-		//try to unpack inner abi type
-		if _, ok := gBaseTypes[typ]; ok {
-			for i := 0; i < count; i++ {
-				v, err := t.unpackAbiStructField(dec, typ)
-				if err != nil {
-					return newError(err)
-				}
-				arr = append(arr, v)
-			}
-			result.Set(name, arr)
-			continue
-		}
-
-		subStruct = t.GetAbiStruct(typ)
-		if subStruct == nil {
-			return newErrorf("unknown type %s", typ)
-		}
-		for i := 0; i < count; i++ {
-			subResult := orderedmap.New()
-			err := t.UnpackAbiStruct(dec, typ, subResult)
-			if err != nil {
-				return newError(err)
-			}
-			arr = append(arr, subResult)
+			return err
 		}
-		result.Set(name, arr)
+		result.Set(v.Name, value)
 	}
 	return nil
 }
 
-func (t *ABI) PackAbiValue(enc *Encoder, typ string, abiValue JsonValue) error {
-	for i := range t.Types {
-		v := &t.Types[i]
-		if v.NewTypeName == typ {
-			typ = v.Type
-			break
+func (t *ABI) packAbiJsonValue(enc *Encoder, typ string, abiValue JsonValue) error {
+	typ = t.getCache().resolve(typ)
+
+	kind, elem, _ := parseAbiType(typ)
+	switch kind {
+	case abiTypeOptional:
+		if abiValue.IsNull() {
+			enc.PackBool(false)
+			return nil
 		}
+		enc.PackBool(true)
+		return t.PackAbiValue(enc, elem, abiValue)
+	case abiTypeArray, abiTypeFixedArray:
+		v, ok := abiValue.GetValue().([]JsonValue)
+		if !ok {
+			return newErrorf("invalid %s value: %v", typ, abiValue.GetValue())
+		}
+		return t.PackArrayAbiValue(enc, typ, v)
 	}
 
 	switch v := abiValue.GetValue().(type) {
-	case string:
-		err := t.ParseAbiStringValue(enc, typ, v)
+	case nil, bool, json.Number, string:
+		text, err := t.abiValueText(typ, abiValue)
 		if err != nil {
-			return newError(err)
+			return err
 		}
-	case JsonValue:
-		err := t.PackAbiValue(enc, typ, v)
+		err = t.ParseAbiStringValue(enc, typ, text)
 		if err != nil {
 			return newError(err)
 		}
 	case []JsonValue:
-		if varType, ok := t.GetVariantType(typ); ok {
-			if len(v) != 2 {
-				return newErrorf("Invalid variant value %v", v)
-			}
-			innerType, ok := v[0].GetStringValue()
-			if !ok {
-				return newErrorf("+++++Invalid variant value %v", v)
-			}
-			found := false
-			for i, variantType := range varType.Types {
-				if variantType == innerType {
-					enc.PackUint8(uint8(i))
-					t.PackAbiValue(enc, variantType, v[1])
-					found = true
-					break
+		varType, ok := t.GetVariantType(typ)
+		if !ok {
+			return newErrorf("invalid %s value: %v", typ, v)
+		}
+		if len(v) != 2 {
+			return newErrorf("Invalid variant value %v", v)
+		}
+		innerType, ok := v[0].GetStringValue()
+		if !ok {
+			return newErrorf("Invalid variant value %v", v)
+		}
+		found := false
+		for i, variantType := range varType.Types {
+			if variantType == innerType {
+				enc.PackUint8(uint8(i))
+				err := t.PackAbiValue(enc, variantType, v[1])
+				if err != nil {
+					return newError(err)
 				}
+				found = true
+				break
 			}
-			if !found {
-				return newErrorf("type %s not found in variant %v", innerType, typ)
-			}
-		} else {
-			err := t.PackArrayAbiValue(enc, typ, v)
-			if err != nil {
-				return newError(err)
-			}
+		}
+		if !found {
+			return newErrorf("type %s not found in variant %v", innerType, typ)
 		}
 	case map[string]JsonValue:
 		err := t.PackAbiStruct(enc, typ, v)
@@ -985,59 +990,108 @@ func (t *ABI) PackAbiValue(enc *Encoder, typ string, abiValue JsonValue) error {
 	return nil
 }
 
-func (t *ABI) GetBaseName(structName string) (string, bool) {
-	for j := range t.Types {
-		s := &t.Types[j]
-		if s.NewTypeName == structName {
-			return s.Type, true
-		}
-	}
-	return "", false
+// SetStrictMode makes numeric and bool abi types only accept json numbers and bools,
+// by default numbers and bools in strings are accepted too
+func (t *ABI) SetStrictMode(strict bool) {
+	t.strict = strict
 }
 
-func (t *ABI) GetAbiStruct(structName string) *ABIStruct {
-	for i := range t.Types {
-		v := &t.Types[i]
-		if v.NewTypeName == structName {
-			structName = v.Type
-			break
-		}
+// abiValueText returns value as the json text that ParseAbiStringValue parses as typ
+func (t *ABI) abiValueText(typ string, value JsonValue) (string, error) {
+	switch v := value.GetValue().(type) {
+	case nil:
+		return "", newErrorf("invalid %s value: null", typ)
+	case bool:
+		return strconv.FormatBool(v), nil
+	case json.Number:
+		return string(v), nil
 	}
 
-	for j := range t.Structs {
-		s := &t.Structs[j]
-		if s.Name == structName {
-			return s
+	v, _ := value.GetStringValue()
+	switch typ {
+	case "bool", "int8", "uint8", "int16", "uint16", "int32", "uint32", "varint32", "varuint32", "float32", "float64":
+		if t.strict {
+			return "", newErrorf("invalid %s value: %s, strings are not accepted in strict mode", typ, strconv.Quote(v))
+		}
+		return v, nil
+	//64 and 128 bit integers are returned in strings by nodeos as they do not fit in a double
+	case "int64", "uint64":
+		return v, nil
+	case "int128", "uint128":
+		if strings.HasPrefix(v, "0x") {
+			return strconv.Quote(v), nil
 		}
+		return v, nil
 	}
-	return nil
+	return strconv.Quote(v), nil
+}
+
+func (t *ABI) GetBaseName(structName string) (string, bool) {
+	return t.GetBaseABIType(structName)
+}
+
+func (t *ABI) GetAbiStruct(structName string) *ABIStruct {
+	cache := t.getCache()
+	return cache.structs[cache.resolve(structName)]
 }
 
 func (t *ABI) GetActionStruct(actionName string) *ABIStruct {
-	if t.Actions == nil {
+	cache := t.getCache()
+	typ, ok := cache.actions[actionName]
+	if !ok {
 		return nil
 	}
+	return cache.structs[typ]
+}
 
-	for i := range t.Actions {
-		action := &t.Actions[i]
-		if action.Name == actionName {
-			for j := range t.Structs {
-				s := &t.Structs[j]
-				if s.Name == action.Type {
-					return s
-				}
-			}
+func (t *ABI) GetActionStructType(actionName string) string {
+	return t.getCache().actions[actionName]
+}
+
+// GetActionResultType returns the type of the value returned by actionName, or an empty string
+func (t *ABI) GetActionResultType(actionName string) string {
+	return t.getCache().actionResults[actionName]
+}
+
+// unpackAbiValue unpacks a value of any abi type, not only structs
+func (t *ABI) unpackAbiValue(dec *Decoder, typ string) (interface{}, error) {
+	//try to find base type
+	typ = t.getCache().resolve(typ)
+
+	if v, ok, err := unpackModifiedValue(dec, typ, t.unpackAbiValue); ok {
+		return v, err
+	}
+
+	//try to unpack inner abi type
+	if _, ok := gBaseTypes[typ]; ok {
+		return t.unpackAbiStructField(dec, typ)
+	}
+
+	//try to unpack Abi struct
+	if t.GetAbiStruct(typ) != nil {
+		result := orderedmap.New()
+		err := t.UnpackAbiStruct(dec, typ, result)
+		if err != nil {
+			return nil, newError(err)
 		}
+		return result, nil
 	}
-	return nil
-}
 
-func (t *ABI) GetActionStructType(actionName string) string {
-	for i := range t.Actions {
-		action := &t.Actions[i]
-		if action.Name == actionName {
-			return action.Type
+	//try to unpack variant type
+	if v, ok := t.GetVariantType(typ); ok {
+		index, err := dec.UnpackUint8()
+		if err != nil {
+			return nil, newError(err)
+		}
+		if int(index) >= len(v.Types) {
+			return nil, newErrorf("invalid variant index %d", index)
+		}
+		tp := v.Types[int(index)]
+		value, err := t.unpackAbiValue(dec, tp)
+		if err != nil {
+			return nil, err
 		}
+		return []interface{}{tp, value}, nil
 	}
-	return ""
+	return nil, newErrorf("unknown type %s", typ)
 }
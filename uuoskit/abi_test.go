@@ -2,12 +2,15 @@ package uuoskit
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"io/ioutil"
 	"log"
+	"strings"
 
 	"fmt"
 	"testing"
 
+	"github.com/iancoleman/orderedmap"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -189,6 +192,49 @@ func TestPackAbiType(t *testing.T) {
 	// t.Log(hex.EncodeToString(r))
 }
 
+func TestPackAbiTypeStrictMode(t *testing.T) {
+	assert := assert.New(t)
+
+	//numbers and bools in strings are accepted by default
+	AssertPackAbiValue(t, "uint32", `"10"`, "0a000000")
+	AssertPackAbiValue(t, "float64", `"1.5"`, "000000000000f83f")
+	AssertPackAbiValue(t, "bool", `"true"`, "01")
+	AssertPackAbiValue(t, "bool", "true", "01")
+	AssertPackAbiValue(t, "uint64", `"18446744073709551615"`, "ffffffffffffffff")
+	AssertPackAbiValue(t, "int128", `"-1"`, "ffffffffffffffffffffffffffffffff")
+	AssertPackAbiValueError(t, "uint32", "null", fmt.Errorf("invalid uint32 value: null"))
+
+	s := NewABISerializer()
+	s.SetStrictMode(true)
+	err := s.SetContractABI("test", []byte(fmt.Sprintf(gAbi, "uint32")))
+	if err != nil {
+		panic(err)
+	}
+
+	r, err := s.PackAbiType("test", "test", `{"t": 10}`)
+	assert.Nil(err)
+	assert.Equal("0a000000", hex.EncodeToString(r))
+	_, err = s.PackAbiType("test", "test", `{"t": "10"}`)
+	assert.Contains(err.Error(), `invalid uint32 value: "10", strings are not accepted in strict mode`)
+
+	//64 bit integers are accepted in strings in strict mode
+	err = s.SetContractABI("test", []byte(fmt.Sprintf(gAbi, "uint64")))
+	if err != nil {
+		panic(err)
+	}
+	r, err = s.PackAbiType("test", "test", `{"t": "10"}`)
+	assert.Nil(err)
+	assert.Equal("0a00000000000000", hex.EncodeToString(r))
+
+	s.SetStrictMode(false)
+	err = s.SetContractABI("test", []byte(fmt.Sprintf(gAbi, "bool")))
+	if err != nil {
+		panic(err)
+	}
+	_, err = s.PackAbiType("test", "test", `{"t": "true"}`)
+	assert.Nil(err)
+}
+
 func TestBuyRam(t *testing.T) {
 	//read abi from file
 	abi, err := ioutil.ReadFile("./data/eosio.system.abi")
@@ -801,3 +847,474 @@ func TestVariant(t *testing.T) {
 	}
 	t.Logf("%s", string(r))
 }
+
+func TestNestedAbiTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	abi := `{
+		"version": "eosio::abi/1.1",
+		"types": [{"new_type_name": "ids", "type": "uint16[]"}],
+		"structs": [
+			{"name": "point", "base": "", "fields": [{"name": "x", "type": "uint16"}]},
+			{"name": "test", "base": "", "fields": [
+				{"name": "a", "type": "uint16?[]"},
+				{"name": "b", "type": "uint16[]?"},
+				{"name": "c", "type": "ids[]"},
+				{"name": "d", "type": "checksum160[2]"},
+				{"name": "e", "type": "shape[]"},
+				{"name": "f", "type": "point?[]"},
+				{"name": "g", "type": "uint16[2]?"},
+				{"name": "h", "type": "uint16[]?$"}
+			]}
+		],
+		"actions": [{"name": "test", "type": "test", "ricardian_contract": ""}],
+		"variants": [{"name": "shape", "types": ["uint16", "point", "string[]"]}]
+	}`
+	ser := NewABISerializer()
+	err := ser.SetContractABI("test", []byte(abi))
+	if err != nil {
+		panic(err)
+	}
+
+	args := `{"a":[1,null],"b":null,"c":[[1,2],[]],"d":["aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"],` +
+		`"e":[["uint16",3],["point",{"x":4}],["string[]",["hello"]]],"f":[{"x":5},null],"g":[6,7],"h":[8]}`
+	r, err := ser.PackActionArgs("test", "test", args)
+	if err != nil {
+		panic(err)
+	}
+	expected := "02" + "01" + "0100" + "00" + //a
+		"00" + //b
+		"02" + "02" + "0100" + "0200" + "00" + //c
+		strings.Repeat("aa", 20) + strings.Repeat("bb", 20) + //d, no length prefix
+		"03" + "00" + "0300" + "01" + "0400" + "02" + "01" + "0568656c6c6f" + //e
+		"02" + "01" + "0500" + "00" + //f
+		"01" + "0600" + "0700" + //g
+		"01" + "01" + "0800" //h
+	assert.Equal(expected, hex.EncodeToString(r))
+
+	r, err = ser.UnpackActionArgs("test", "test", r)
+	assert.Nil(err)
+	assert.Equal(args, string(r))
+
+	//binary extensions can be left out
+	packed, err := ser.PackActionArgs("test", "test", strings.Replace(args, `,"h":[8]`, "", 1))
+	assert.Nil(err)
+	r, err = ser.UnpackActionArgs("test", "test", packed)
+	assert.Nil(err)
+	assert.NotContains(string(r), `"h"`)
+
+	_, err = ser.PackActionArgs("test", "test", strings.Replace(args, `"g":[6,7]`, `"g":[6]`, 1))
+	assert.Contains(err.Error(), "uint16[2] requires 2 values, got 1")
+
+	_, err = ser.UnpackActionArgs("test", "test", []byte{2, 1})
+	assert.NotNil(err)
+
+	//fixed size arrays have to have a positive size
+	err = ser.SetContractABI("test", []byte(strings.Replace(abi, "uint16[2]?", "uint16[0]?", 1)))
+	assert.Contains(err.Error(), "struct test field g: unknown type uint16[0]?")
+	//huge array lengths do not allocate more than the remaining bytes
+	_, err = ser.UnpackActionArgs("test", "test", []byte{0xff, 0xff, 0xff, 0xff, 0x0f})
+	assert.NotNil(err)
+	err = ser.SetContractABI("huge", []byte(strings.Replace(abi, `"type": "uint16?[]"`, `"type": "uint64[4000000000]"`, 1)))
+	if err != nil {
+		panic(err)
+	}
+	_, err = ser.UnpackActionArgs("huge", "test", []byte{0})
+	assert.NotNil(err)
+
+	//structs without fields take no bytes, arrays of them are longer than the packed data
+	err = ser.SetContractABI("empty", []byte(`{
+		"version": "eosio::abi/1.1",
+		"structs": [
+			{"name": "empty", "base": "", "fields": []},
+			{"name": "test", "base": "", "fields": [{"name": "a", "type": "empty[]"}]}
+		],
+		"actions": [{"name": "test", "type": "test", "ricardian_contract": ""}]
+	}`))
+	if err != nil {
+		panic(err)
+	}
+	r, err = ser.PackActionArgs("empty", "test", `{"a":[{},{},{}]}`)
+	assert.Nil(err)
+	assert.Equal("03", hex.EncodeToString(r))
+	r, err = ser.UnpackActionArgs("empty", "test", r)
+	assert.Nil(err)
+	assert.Equal(`{"a":[{},{},{}]}`, string(r))
+}
+
+func TestActionResults(t *testing.T) {
+	assert := assert.New(t)
+
+	ser := NewABISerializer()
+	rawAbi, err := ser.PackABI(testReturnValueAbi)
+	if err != nil {
+		panic(err)
+	}
+
+	abi, err := ser.UnpackABI(rawAbi)
+	if err != nil {
+		panic(err)
+	}
+	assert.Contains(abi, `"action_results":[{"name":"sayhello","result_type":"string"},{"name":"greet","result_type":"greeting"}]`)
+
+	rawAbi2, err := ser.PackABI(abi)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(rawAbi, rawAbi2)
+
+	err = ser.SetContractABI("hello", []byte(abi))
+	if err != nil {
+		panic(err)
+	}
+
+	r, err := ser.UnpackActionResult("hello", "sayhello", []byte("\x05hello"))
+	assert.Nil(err)
+	assert.Equal(`"hello"`, string(r))
+
+	r, err = ser.UnpackActionResult("hello", "greet", []byte{0, 0, 0, 0, 0, 0x1a, 0xa3, 0x6a, 3, 0, 0, 0})
+	assert.Nil(err)
+	assert.Equal(`{"to":"hello","count":3}`, string(r))
+
+	_, err = ser.UnpackActionResult("hello", "unknown", []byte{})
+	assert.NotNil(err)
+
+	_, err = ser.UnpackActionResult("nobody", "sayhello", []byte{})
+	assert.NotNil(err)
+
+	//abis without action_results are not changed
+	rawAbi, err = ser.PackABI(eosioTokenAbi)
+	if err != nil {
+		panic(err)
+	}
+	abi, err = ser.UnpackABI(rawAbi)
+	assert.Nil(err)
+	assert.NotContains(abi, "action_results")
+}
+
+// abi of the kv_map example contract of EOSIO 2.1
+var testKvTablesAbi = `{
+	"version": "eosio::abi/1.2",
+	"types": [],
+	"structs": [
+		{"name": "person", "base": "", "fields": [
+			{"name": "account_name", "type": "name"},
+			{"name": "first_name", "type": "string"},
+			{"name": "last_name", "type": "string"},
+			{"name": "personal_id", "type": "string"}
+		]},
+		{"name": "get", "base": "", "fields": [{"name": "account_name", "type": "name"}]},
+		{"name": "upsert", "base": "", "fields": [{"name": "p", "type": "person"}]}
+	],
+	"actions": [
+		{"name": "get", "type": "get", "ricardian_contract": ""},
+		{"name": "upsert", "type": "upsert", "ricardian_contract": ""}
+	],
+	"tables": [],
+	"kv_tables": {
+		"people": {
+			"type": "person",
+			"primary_index": {"name": "map.index", "type": "name"},
+			"secondary_indices": {
+				"personid": {"type": "string"},
+				"fullname": {"type": "string"}
+			}
+		},
+		"address": {
+			"type": "person",
+			"primary_index": {"name": "map.index", "type": "name"},
+			"secondary_indices": {}
+		}
+	},
+	"ricardian_clauses": [],
+	"variants": [],
+	"action_results": [{"name": "get", "result_type": "person"}]
+}`
+
+func TestAbiRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	ser := NewABISerializer()
+
+	roundTrip := func(abi string) ([]byte, string) {
+		rawAbi, err := ser.PackABI(abi)
+		if err != nil {
+			panic(err)
+		}
+
+		unpacked, err := ser.UnpackABI(rawAbi)
+		if err != nil {
+			panic(err)
+		}
+
+		rawAbi2, err := ser.PackABI(unpacked)
+		if err != nil {
+			panic(err)
+		}
+		assert.Equal(rawAbi, rawAbi2)
+
+		unpacked2, err := ser.UnpackABI(rawAbi2)
+		if err != nil {
+			panic(err)
+		}
+		assert.Equal(unpacked, unpacked2)
+		return rawAbi, unpacked
+	}
+
+	atomicAssetsAbi, err := ioutil.ReadFile("data/atomicassets.abi")
+	if err != nil {
+		panic(err)
+	}
+	_, abi := roundTrip(string(atomicAssetsAbi))
+	assert.Contains(abi, `"variants":[{`)
+	assert.Contains(abi, `"action_results":[]`)
+	assert.NotContains(abi, "kv_tables")
+
+	rawAbi, abi := roundTrip(testKvTablesAbi)
+	assert.Contains(abi, `"action_results":[{"name":"get","result_type":"person"}]`)
+	assert.Contains(abi, `"kv_tables":{"address":{"type":"person","primary_index":{"name":"map.index","type":"name"},"secondary_indices":{}},"people":`)
+	//kv tables and their indices are packed in the order of names
+	assert.True(strings.HasSuffix(hex.EncodeToString(rawAbi), "02"+hex.EncodeToString(packName("address"))+"06706572736f6e"+hex.EncodeToString(packName("map.index"))+"046e616d6500"+
+		hex.EncodeToString(packName("people"))+"06706572736f6e"+hex.EncodeToString(packName("map.index"))+"046e616d6502"+
+		hex.EncodeToString(packName("fullname"))+"06737472696e67"+hex.EncodeToString(packName("personid"))+"06737472696e67"))
+
+	//abi 1.0 without variants
+	rawAbi, abi = roundTrip(`{"version": "eosio::abi/1.0", "types": [], "structs": [], "actions": [], "tables": [], "ricardian_clauses": []}`)
+	assert.Equal("0e656f73696f3a3a6162692f312e30"+strings.Repeat("00", 7), hex.EncodeToString(rawAbi))
+	assert.NotContains(abi, "variants")
+
+	//abi 1.1 packed by EOSIO 2.1 with all binary extensions
+	rawAbi, abi = roundTrip(`{"version": "eosio::abi/1.1", "variants": [], "action_results": [], "kv_tables": {}}`)
+	assert.Equal("0e656f73696f3a3a6162692f312e31"+strings.Repeat("00", 7+3), hex.EncodeToString(rawAbi))
+	assert.Contains(abi, `"variants":[],"action_results":[],"kv_tables":{}`)
+
+	//abi 1.3 packed by Leap with action_results
+	rawAbi, abi = roundTrip(`{"version": "eosio::abi/1.3", "action_results": []}`)
+	assert.Equal("0e656f73696f3a3a6162692f312e33"+strings.Repeat("00", 7+2), hex.EncodeToString(rawAbi))
+	assert.NotContains(abi, "kv_tables")
+
+	_, err = ser.PackABI(`{"version": "eosio::abi/2.0"}`)
+	assert.Contains(err.Error(), "unsupported abi version")
+
+	_, err = ser.UnpackABI(append(rawAbi, 0, 0))
+	assert.Contains(err.Error(), "bytes left")
+
+	//sections are packed by their presence, any 1.x version is accepted
+	rawAbi, abi = roundTrip(`{"version": "eosio::abi/1.1", "action_results": [{"name": "get", "result_type": "person"}]}`)
+	assert.True(strings.HasSuffix(hex.EncodeToString(rawAbi), "0001"+hex.EncodeToString(packName("get"))+"06706572736f6e"))
+	_, abi = roundTrip(`{"version": "eosio::abi/1.0", "variants": [{"name": "v", "types": ["int8"]}]}`)
+	assert.Contains(abi, `"variants":[{"name":"v","types":["int8"]}]`)
+	assert.NotContains(abi, "action_results")
+	rawAbi, abi = roundTrip(`{"version": "eosio::abi/1.9"}`)
+	assert.Equal("0e656f73696f3a3a6162692f312e39"+strings.Repeat("00", 7), hex.EncodeToString(rawAbi))
+	assert.NotContains(abi, "variants")
+}
+
+func packName(name string) []byte {
+	n := NewName(name)
+	return n.Pack()
+}
+
+func TestAbiValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, abi := range []string{eosioTokenAbi, testKvTablesAbi, testReturnValueAbi} {
+		assert.Nil(NewABISerializer().SetContractABI("test", []byte(abi)))
+	}
+
+	abi := `{
+		"version": "eosio::abi/1.2",
+		"types": [
+			{"new_type_name": "account_name", "type": "name"},
+			{"new_type_name": "loop1", "type": "loop2"},
+			{"new_type_name": "loop2", "type": "loop1"},
+			{"new_type_name": "names", "type": "account_name[]"},
+			{"new_type_name": "uint64", "type": "uint32"},
+			{"new_type_name": "account_name", "type": "name"}
+		],
+		"structs": [
+			{"name": "base", "base": "derived", "fields": []},
+			{"name": "derived", "base": "base", "fields": []},
+			{"name": "transfer", "base": "", "fields": [
+				{"name": "from", "type": "account_name"},
+				{"name": "to", "type": "acount_name"},
+				{"name": "memo", "type": "string$"},
+				{"name": "to", "type": "names?"}
+			]},
+			{"name": "issue", "base": "unknown", "fields": [{"name": "loop", "type": "loop1"}]},
+			{"name": "account_name", "base": "", "fields": []}
+		],
+		"variants": [
+			{"name": "value", "types": ["uint64", "transfer", "asset[]", "symbl"]}
+		],
+		"actions": [
+			{"name": "transfer", "type": "transfer", "ricardian_contract": ""},
+			{"name": "transfer", "type": "transfer", "ricardian_contract": ""},
+			{"name": "Issue", "type": "issue", "ricardian_contract": ""},
+			{"name": "retire", "type": "retire", "ricardian_contract": ""}
+		],
+		"tables": [
+			{"name": "accounts.", "index_type": "i64", "key_names": [], "key_types": [], "type": "value"}
+		],
+		"action_results": [
+			{"name": "transfer", "result_type": "value[]"}
+		],
+		"kv_tables": {
+			"people": {"type": "person", "primary_index": {"name": "map.index", "type": "name"}, "secondary_indices": {"byname": {"type": "sring"}}}
+		}
+	}`
+
+	err := NewABISerializer().SetContractABI("test", []byte(abi))
+	e, ok := err.(*ABIValidationError)
+	assert.True(ok)
+	assert.Equal([]string{
+		"type uint64: redefines a built-in type",
+		"type account_name: duplicated",
+		"struct account_name: already defined as type",
+		"type loop1: circular typedef",
+		"type loop2: circular typedef",
+		"struct base: circular base derived",
+		"struct derived: circular base base",
+		"struct transfer field to: unknown type acount_name",
+		"struct transfer field to: duplicated",
+		"struct transfer field to: follows a binary extension field",
+		"struct issue: base unknown is not a struct",
+		"struct issue field loop: unknown type loop1",
+		"variant value: unknown type symbl",
+		"action transfer: duplicated",
+		`action: invalid name "Issue"`,
+		"action retire: unknown type retire",
+		`table: invalid name "accounts."`,
+		"kv table people: unknown type person",
+		"kv table people secondary index byname: unknown type sring",
+	}, e.Problems)
+	assert.Contains(err.Error(), "invalid abi: type uint64: redefines a built-in type; type account_name: duplicated")
+
+	assert.True(IsValidName("eosio.token"))
+	assert.True(IsValidName("zzzzzzzzzzzzj"))
+	assert.True(IsValidName(""))
+	assert.False(IsValidName("zzzzzzzzzzzzk"))
+	assert.False(IsValidName("zzzzzzzzzzzzzz"))
+	assert.False(IsValidName("hello."))
+	assert.False(IsValidName("hello6"))
+	assert.False(IsValidName("Hello"))
+}
+
+var benchmarkMintAssetArgs = `{
+	"authorized_minter": "alice",
+	"collection_name": "collection1",
+	"schema_name": "schema1",
+	"template_id": 12,
+	"new_asset_owner": "bob",
+	"immutable_data": [{"key": "name", "value": ["string", "sword"]}, {"key": "level", "value": ["uint32", 10]}],
+	"mutable_data": [{"key": "durability", "value": ["uint16", 100]}],
+	"tokens_to_back": ["1.0000 EOS"]
+}`
+
+// newBenchmarkAbi returns the atomicassets abi with fillers structs and typedefs
+// in front of its own, as in the abis of large contracts
+func newBenchmarkAbi(fillers int) *ABI {
+	data, err := ioutil.ReadFile("data/atomicassets.abi")
+	if err != nil {
+		panic(err)
+	}
+
+	abi := &ABI{}
+	if err := json.Unmarshal(data, abi); err != nil {
+		panic(err)
+	}
+
+	types := []ABIType{}
+	structs := []ABIStruct{}
+	for i := 0; i < fillers; i++ {
+		types = append(types, ABIType{NewTypeName: fmt.Sprintf("type%d", i), Type: "uint64"})
+		structs = append(structs, ABIStruct{Name: fmt.Sprintf("struct%d", i), Fields: []ABIStructField{{Name: "a", Type: "uint64"}}})
+	}
+	abi.Types = append(types, abi.Types...)
+	abi.Structs = append(structs, abi.Structs...)
+	return abi
+}
+
+func benchmarkPackAbiStruct(b *testing.B, fillers int) {
+	abi := newBenchmarkAbi(fillers)
+	args := make(map[string]JsonValue)
+	if err := json.Unmarshal([]byte(benchmarkMintAssetArgs), &args); err != nil {
+		panic(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		enc := NewEncoder(256)
+		if err := abi.PackAbiStruct(enc, "mintasset", args); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func benchmarkUnpackAbiStruct(b *testing.B, fillers int) {
+	abi := newBenchmarkAbi(fillers)
+	packed, err := abi.PackAbiType("mintasset", benchmarkMintAssetArgs)
+	if err != nil {
+		panic(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := abi.UnpackAbiStruct(NewDecoder(packed), "mintasset", orderedmap.New()); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func BenchmarkPackAbiStruct(b *testing.B) {
+	benchmarkPackAbiStruct(b, 0)
+}
+
+func BenchmarkPackAbiStructLargeAbi(b *testing.B) {
+	benchmarkPackAbiStruct(b, 500)
+}
+
+func BenchmarkUnpackAbiStruct(b *testing.B) {
+	benchmarkUnpackAbiStruct(b, 0)
+}
+
+func BenchmarkUnpackAbiStructLargeAbi(b *testing.B) {
+	benchmarkUnpackAbiStruct(b, 500)
+}
+
+func newBenchmarkSerializer() *ABISerializer {
+	abi, err := ioutil.ReadFile("data/atomicassets.abi")
+	if err != nil {
+		panic(err)
+	}
+
+	ser := NewABISerializer()
+	if err := ser.SetContractABI("atomicassets", abi); err != nil {
+		panic(err)
+	}
+	return ser
+}
+
+func BenchmarkPackActionArgs(b *testing.B) {
+	ser := newBenchmarkSerializer()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ser.PackActionArgs("atomicassets", "mintasset", benchmarkMintAssetArgs); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func BenchmarkUnpackActionArgs(b *testing.B) {
+	ser := newBenchmarkSerializer()
+	packed, err := ser.PackActionArgs("atomicassets", "mintasset", benchmarkMintAssetArgs)
+	if err != nil {
+		panic(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ser.UnpackActionArgs("atomicassets", "mintasset", packed); err != nil {
+			panic(err)
+		}
+	}
+}
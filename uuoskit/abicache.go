@@ -0,0 +1,121 @@
+package uuoskit
+
+// abiCache is the resolved type graph of an ABI. It is built once, by SetContractABI or by the first
+// pack or unpack, so that they do not scan Types, Structs and Variants for every field.
+// Changes made to an ABI after that are not seen by the cache
+type abiCache struct {
+	//new_type_name to the type at the end of the typedef chain
+	typedefs map[string]string
+	structs  map[string]*ABIStruct
+	//fields of structs, the fields of their bases come first
+	fields        map[string][]ABIStructField
+	variants      map[string]*VariantDef
+	actions       map[string]string
+	actionResults map[string]string
+}
+
+func newAbiCache(abi *ABI) *abiCache {
+	c := &abiCache{
+		typedefs:      make(map[string]string, len(abi.Types)),
+		structs:       make(map[string]*ABIStruct, len(abi.Structs)),
+		fields:        make(map[string][]ABIStructField, len(abi.Structs)),
+		variants:      make(map[string]*VariantDef, len(abi.Variants)),
+		actions:       make(map[string]string, len(abi.Actions)),
+		actionResults: make(map[string]string, len(abi.ActionResults)),
+	}
+
+	//the first definition wins, as with the linear scans
+	next := make(map[string]string, len(abi.Types))
+	for i := range abi.Types {
+		typ := &abi.Types[i]
+		if _, ok := next[typ.NewTypeName]; !ok {
+			next[typ.NewTypeName] = typ.Type
+		}
+	}
+
+	for name := range next {
+		typ := name
+		for depth := 0; depth <= len(next); depth++ {
+			n, ok := next[typ]
+			if !ok {
+				break
+			}
+			typ = n
+		}
+		c.typedefs[name] = typ
+	}
+
+	for i := range abi.Structs {
+		s := &abi.Structs[i]
+		if _, ok := c.structs[s.Name]; !ok {
+			c.structs[s.Name] = s
+		}
+	}
+
+	for name := range c.structs {
+		c.flattenFields(name, make(map[string]bool))
+	}
+
+	for i := range abi.Variants {
+		v := &abi.Variants[i]
+		if _, ok := c.variants[v.Name]; !ok {
+			c.variants[v.Name] = v
+		}
+	}
+
+	for i := range abi.Actions {
+		a := &abi.Actions[i]
+		if _, ok := c.actions[a.Name]; !ok {
+			c.actions[a.Name] = a.Type
+		}
+	}
+
+	for i := range abi.ActionResults {
+		r := &abi.ActionResults[i]
+		if _, ok := c.actionResults[r.Name]; !ok {
+			c.actionResults[r.Name] = r.ResultType
+		}
+	}
+	return c
+}
+
+// flattenFields caches the fields of a struct and its bases, structs with missing or circular bases are not cached
+func (c *abiCache) flattenFields(name string, visiting map[string]bool) ([]ABIStructField, bool) {
+	if fields, ok := c.fields[name]; ok {
+		return fields, true
+	}
+
+	s, ok := c.structs[name]
+	if !ok || visiting[name] {
+		return nil, false
+	}
+
+	fields := make([]ABIStructField, 0, len(s.Fields))
+	if s.Base != "" {
+		visiting[name] = true
+		baseFields, ok := c.flattenFields(c.resolve(s.Base), visiting)
+		delete(visiting, name)
+		if !ok {
+			return nil, false
+		}
+		fields = append(fields, baseFields...)
+	}
+	fields = append(fields, s.Fields...)
+	c.fields[name] = fields
+	return fields, true
+}
+
+// resolve follows the typedef chain of typ
+func (c *abiCache) resolve(typ string) string {
+	if resolved, ok := c.typedefs[typ]; ok {
+		return resolved
+	}
+	return typ
+}
+
+func (t *ABI) getCache() *abiCache {
+	if t.cache == nil {
+		t.cache = newAbiCache(t)
+	}
+	return t.cache
+}
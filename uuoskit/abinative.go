@@ -0,0 +1,655 @@
+package uuoskit
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// PackAbiValue packs v as typ to enc. v is either a JsonValue or made of native Go values, which are packed
+// without a json round trip. The values UnpackAbiValue returns are accepted, and besides them integer types accept
+// any Go integer, float types accept any Go number, time_point and time_point_sec accept time.Time,
+// int128 and uint128 accept *big.Int, arrays accept any slice or array, optionals accept nil pointers and
+// structs accept Go structs, whose fields are matched by their json tags or the snake case of their names.
+// Built-in types also accept strings and json.Number in the format of their json values, e.g. "1.0000 EOS" for asset
+func (t *ABI) PackAbiValue(enc *Encoder, typ string, v interface{}) error {
+	switch value := v.(type) {
+	case JsonValue:
+		return t.packAbiJsonValue(enc, typ, value)
+	case *JsonValue:
+		return t.packAbiJsonValue(enc, typ, *value)
+	}
+	return t.packNativeValue(enc, typ, v)
+}
+
+// UnpackAbiValue unpacks b as typ to native Go values: structs are unpacked to map[string]interface{},
+// arrays to []interface{}, empty optionals to nil, variants to []interface{}{typeName, value}, and built-in types to
+// bool, int8 to uint64, int32 for varint32, uint32 for varuint32, float32, float64, Int128, Uint128, Float128,
+// TimePoint, TimePointSec, BlockTimestampType, Name, Bytes, string, [20]byte, [32]byte and [64]byte for checksums,
+// PublicKey, Signature, Symbol, string for symbol_code, Asset and ExtendedAsset
+func (t *ABI) UnpackAbiValue(typ string, b []byte) (interface{}, error) {
+	return t.unpackNativeValue(NewDecoder(b), typ)
+}
+
+func isNilValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+func (t *ABI) packNativeValue(enc *Encoder, typ string, v interface{}) error {
+	typ = t.getCache().resolve(typ)
+
+	kind, elem, size := parseAbiType(typ)
+	switch kind {
+	case abiTypeOptional:
+		if isNilValue(v) {
+			enc.PackBool(false)
+			return nil
+		}
+		enc.PackBool(true)
+		return t.packNativeValue(enc, elem, v)
+	case abiTypeArray, abiTypeFixedArray:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return newErrorf("invalid %s value: %T", typ, v)
+		}
+		if kind == abiTypeArray {
+			enc.PackLength(rv.Len())
+		} else if rv.Len() != size {
+			return newErrorf("%s requires %d values, got %d", typ, size, rv.Len())
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := t.packNativeValue(enc, elem, rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if _, ok := gBaseTypes[typ]; ok {
+		return t.packNativeBaseValue(enc, typ, v)
+	}
+
+	if t.GetAbiStruct(typ) != nil {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			m, ok = nativeStructFields(v)
+		}
+		if !ok {
+			return newErrorf("invalid %s value: %T", typ, v)
+		}
+		fields, err := t.getStructFields(typ)
+		if err != nil {
+			return err
+		}
+		for _, field := range fields {
+			fieldType := field.Type
+			value, ok := m[field.Name]
+			if !ok || isNilValue(value) {
+				//handle binary_extension, nil pointers of Go structs leave them out
+				if strings.HasSuffix(fieldType, "$") {
+					continue
+				}
+			}
+			if !ok {
+				return newErrorf("missing field %s", field.Name)
+			}
+			fieldType = strings.TrimSuffix(fieldType, "$")
+			if err := t.packNativeValue(enc, fieldType, value); err != nil {
+				return newErrorf("%s.%s: %v", typ, field.Name, err)
+			}
+		}
+		return nil
+	}
+
+	if varType, ok := t.GetVariantType(typ); ok {
+		arr, ok := v.([]interface{})
+		if !ok || len(arr) != 2 {
+			return newErrorf("invalid variant value %v", v)
+		}
+		innerType, ok := arr[0].(string)
+		if !ok {
+			return newErrorf("invalid variant value %v", v)
+		}
+		for i, variantType := range varType.Types {
+			if variantType == innerType {
+				enc.PackUint8(uint8(i))
+				return t.packNativeValue(enc, variantType, arr[1])
+			}
+		}
+		return newErrorf("type %s not found in variant %v", innerType, typ)
+	}
+	return newErrorf("unknown type %s", typ)
+}
+
+// snakeCase converts Go field names like OwnerID to owner_id
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			//a new word starts after a lower case letter or a digit, or at the last capital of an acronym
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// nativeStructFields returns the exported fields of v, a Go struct or a pointer to one, by their json tags
+// or the snake case of their names. Fields of embedded structs are included, like encoding/json does
+func nativeStructFields(v interface{}) (map[string]interface{}, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	m := make(map[string]interface{})
+	var collect func(rv reflect.Value)
+	collect = func(rv reflect.Value) {
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			name := ""
+			if tag, ok := field.Tag.Lookup("json"); ok {
+				name = strings.Split(tag, ",")[0]
+				if name == "-" {
+					continue
+				}
+			}
+			if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+				collect(rv.Field(i))
+				continue
+			}
+			if field.PkgPath != "" {
+				continue
+			}
+			if name == "" {
+				name = snakeCase(field.Name)
+			}
+			m[name] = rv.Field(i).Interface()
+		}
+	}
+	collect(rv)
+	return m, true
+}
+
+var nativeIntBits = map[string]uint{
+	"int8":     8,
+	"int16":    16,
+	"int32":    32,
+	"int64":    64,
+	"varint32": 32,
+}
+
+var nativeUintBits = map[string]uint{
+	"uint8":     8,
+	"uint16":    16,
+	"uint32":    32,
+	"uint64":    64,
+	"varuint32": 32,
+}
+
+// nativeInt returns v, any Go integer, if it fits in a signed integer of bits bits
+func nativeInt(typ string, v interface{}, bits uint) (int64, error) {
+	max := int64(1)<<(bits-1) - 1
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := rv.Int()
+		if n > max || n < -max-1 {
+			return 0, newErrorf("%s overflow: %d", typ, n)
+		}
+		return n, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n := rv.Uint()
+		if n > uint64(max) {
+			return 0, newErrorf("%s overflow: %d", typ, n)
+		}
+		return int64(n), nil
+	}
+	return 0, newErrorf("invalid %s value: %T", typ, v)
+}
+
+// nativeUint returns v, any Go integer, if it fits in an unsigned integer of bits bits
+func nativeUint(typ string, v interface{}, bits uint) (uint64, error) {
+	max := uint64(1)<<(bits-1)<<1 - 1
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := rv.Int()
+		if n < 0 || uint64(n) > max {
+			return 0, newErrorf("%s overflow: %d", typ, n)
+		}
+		return uint64(n), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n := rv.Uint()
+		if n > max {
+			return 0, newErrorf("%s overflow: %d", typ, n)
+		}
+		return n, nil
+	}
+	return 0, newErrorf("invalid %s value: %T", typ, v)
+}
+
+// nativeInteger returns v, any Go integer, as a big.Int
+func nativeInteger(v interface{}) (*big.Int, bool) {
+	if n, ok := v.(*big.Int); ok {
+		return n, true
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Int).SetUint64(rv.Uint()), true
+	}
+	return nil, false
+}
+
+// nativeChecksum returns v, a byte array, a byte slice or Bytes, if it has size bytes
+func nativeChecksum(v interface{}, size int) ([]byte, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	if rv.Type().Elem().Kind() != reflect.Uint8 || rv.Len() != size {
+		return nil, false
+	}
+	buf := make([]byte, size)
+	reflect.Copy(reflect.ValueOf(buf), rv)
+	return buf, true
+}
+
+// packInt128 packs n in two's complement little endian
+func packInt128(enc *Encoder, typ string, n *big.Int) error {
+	min := new(big.Int).Lsh(big.NewInt(-1), 127)
+	max := new(big.Int).Lsh(big.NewInt(1), 128)
+	if typ == "int128" {
+		max.Rsh(max, 1)
+	} else {
+		min.SetInt64(0)
+	}
+	if n.Cmp(min) < 0 || n.Cmp(max) >= 0 {
+		return newErrorf("%s overflow: %s", typ, n.String())
+	}
+
+	v := new(big.Int).Set(n)
+	if v.Sign() < 0 {
+		v.Add(v, new(big.Int).Lsh(big.NewInt(1), 128))
+	}
+	buf := make([]byte, 16)
+	v.FillBytes(buf)
+	reverseBytes(buf)
+	enc.WriteBytes(buf)
+	return nil
+}
+
+func (t *ABI) packNativeBaseValue(enc *Encoder, typ string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Type() != reflect.TypeOf(&big.Int{}) {
+		v = rv.Elem().Interface()
+	}
+
+	switch value := v.(type) {
+	case string:
+		if typ == "string" {
+			enc.PackString(value)
+			return nil
+		}
+		text, err := t.abiValueText(typ, NewJsonValue(value))
+		if err != nil {
+			return err
+		}
+		return t.ParseAbiStringValue(enc, typ, text)
+	case json.Number:
+		return t.ParseAbiStringValue(enc, typ, string(value))
+	}
+
+	if bits, ok := nativeIntBits[typ]; ok {
+		n, err := nativeInt(typ, v, bits)
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case "int8":
+			enc.PackInt8(int8(n))
+		case "int16":
+			enc.PackInt16(int16(n))
+		case "int32":
+			enc.PackInt32(int32(n))
+		case "int64":
+			enc.PackInt64(n)
+		case "varint32":
+			enc.PackVarInt32(int32(n))
+		}
+		return nil
+	}
+
+	if bits, ok := nativeUintBits[typ]; ok {
+		n, err := nativeUint(typ, v, bits)
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case "uint8":
+			enc.PackUint8(uint8(n))
+		case "uint16":
+			enc.PackUint16(uint16(n))
+		case "uint32":
+			enc.PackUint32(uint32(n))
+		case "uint64":
+			enc.PackUint64(n)
+		case "varuint32":
+			enc.PackVarUint32(uint32(n))
+		}
+		return nil
+	}
+
+	switch typ {
+	case "bool":
+		if b, ok := v.(bool); ok {
+			enc.PackBool(b)
+			return nil
+		}
+	case "float32", "float64":
+		var f float64
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			f = rv.Float()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f = float64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			f = float64(rv.Uint())
+		default:
+			return newErrorf("invalid %s value: %T", typ, v)
+		}
+		if typ == "float32" {
+			enc.PackFloat32(float32(f))
+		} else {
+			enc.PackFloat64(f)
+		}
+		return nil
+	case "int128":
+		if value, ok := v.(Int128); ok {
+			enc.WriteBytes(value[:])
+			return nil
+		}
+		if n, ok := nativeInteger(v); ok {
+			return packInt128(enc, typ, n)
+		}
+	case "uint128":
+		if value, ok := v.(Uint128); ok {
+			enc.WriteBytes(value[:])
+			return nil
+		}
+		if n, ok := nativeInteger(v); ok {
+			return packInt128(enc, typ, n)
+		}
+	case "float128":
+		if value, ok := v.(Float128); ok {
+			enc.WriteBytes(value[:])
+			return nil
+		}
+	case "time_point":
+		switch value := v.(type) {
+		case TimePoint:
+			enc.PackUint64(value.Elapsed)
+			return nil
+		case time.Time:
+			enc.PackInt64(value.UnixMicro())
+			return nil
+		}
+	case "time_point_sec":
+		switch value := v.(type) {
+		case TimePointSec:
+			enc.PackUint32(value.UTCSeconds)
+			return nil
+		case time.Time:
+			enc.PackUint32(uint32(value.Unix()))
+			return nil
+		}
+	case "block_timestamp_type":
+		if value, ok := v.(BlockTimestampType); ok {
+			enc.PackUint32(value.Slot)
+			return nil
+		}
+	case "name":
+		if value, ok := v.(Name); ok {
+			enc.PackName(value)
+			return nil
+		}
+	case "bytes":
+		switch value := v.(type) {
+		case []byte:
+			enc.PackBytes(value)
+			return nil
+		case Bytes:
+			enc.PackBytes(value)
+			return nil
+		}
+	case "checksum160", "checksum256", "checksum512":
+		size := map[string]int{"checksum160": 20, "checksum256": 32, "checksum512": 64}[typ]
+		if buf, ok := nativeChecksum(v, size); ok {
+			enc.WriteBytes(buf)
+			return nil
+		}
+	case "public_key":
+		if value, ok := v.(PublicKey); ok {
+			enc.WriteBytes(value.Pack())
+			return nil
+		}
+	case "signature":
+		if value, ok := v.(Signature); ok {
+			enc.WriteBytes(value.Pack())
+			return nil
+		}
+	case "symbol":
+		if value, ok := v.(Symbol); ok {
+			enc.PackUint64(value.Value)
+			return nil
+		}
+	case "asset":
+		if value, ok := v.(Asset); ok {
+			enc.WriteBytes(value.Pack())
+			return nil
+		}
+	case "extended_asset":
+		if value, ok := v.(ExtendedAsset); ok {
+			enc.WriteBytes(value.Pack())
+			return nil
+		}
+	}
+	return newErrorf("invalid %s value: %T", typ, v)
+}
+
+func (t *ABI) unpackNativeValue(dec *Decoder, typ string) (interface{}, error) {
+	typ = t.getCache().resolve(typ)
+
+	if v, ok, err := unpackModifiedValue(dec, typ, t.unpackNativeValue); ok {
+		return v, err
+	}
+
+	if _, ok := gBaseTypes[typ]; ok {
+		return t.unpackNativeBaseValue(dec, typ)
+	}
+
+	if t.GetAbiStruct(typ) != nil {
+		fields, err := t.getStructFields(typ)
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			fieldType := field.Type
+			//handle binary_extension
+			if strings.HasSuffix(fieldType, "$") {
+				if dec.IsEnd() {
+					break
+				}
+				fieldType = strings.TrimSuffix(fieldType, "$")
+			}
+			v, err := t.unpackNativeValue(dec, fieldType)
+			if err != nil {
+				return nil, err
+			}
+			m[field.Name] = v
+		}
+		return m, nil
+	}
+
+	if v, ok := t.GetVariantType(typ); ok {
+		index, err := dec.UnpackUint8()
+		if err != nil {
+			return nil, newError(err)
+		}
+		if int(index) >= len(v.Types) {
+			return nil, newErrorf("invalid variant index %d", index)
+		}
+		tp := v.Types[int(index)]
+		value, err := t.unpackNativeValue(dec, tp)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{tp, value}, nil
+	}
+	return nil, newErrorf("unknown type %s", typ)
+}
+
+func (t *ABI) unpackNativeBaseValue(dec *Decoder, typ string) (interface{}, error) {
+	switch typ {
+	case "varuint32":
+		v, err := dec.UnpackVarUint32()
+		if err != nil {
+			return nil, newError(err)
+		}
+		return uint32(v), nil
+	case "int128":
+		v := Int128{}
+		if err := dec.Read(v[:]); err != nil {
+			return nil, newError(err)
+		}
+		return v, nil
+	case "uint128":
+		v := Uint128{}
+		if err := dec.Read(v[:]); err != nil {
+			return nil, newError(err)
+		}
+		return v, nil
+	case "float128":
+		v := Float128{}
+		if err := dec.Read(v[:]); err != nil {
+			return nil, newError(err)
+		}
+		return v, nil
+	case "time_point":
+		v, err := dec.ReadUint64()
+		if err != nil {
+			return nil, newError(err)
+		}
+		return TimePoint{v}, nil
+	case "time_point_sec":
+		v, err := dec.ReadUint32()
+		if err != nil {
+			return nil, newError(err)
+		}
+		return TimePointSec{v}, nil
+	case "block_timestamp_type":
+		v, err := dec.ReadUint32()
+		if err != nil {
+			return nil, newError(err)
+		}
+		return BlockTimestampType{v}, nil
+	case "name":
+		v, err := dec.ReadUint64()
+		if err != nil {
+			return nil, newError(err)
+		}
+		return Name{v}, nil
+	case "bytes":
+		v, err := dec.UnpackBytes()
+		if err != nil {
+			return nil, newError(err)
+		}
+		return Bytes(v), nil
+	case "checksum160":
+		v := [20]byte{}
+		if err := dec.Read(v[:]); err != nil {
+			return nil, newError(err)
+		}
+		return v, nil
+	case "checksum256":
+		v := [32]byte{}
+		if err := dec.Read(v[:]); err != nil {
+			return nil, newError(err)
+		}
+		return v, nil
+	case "checksum512":
+		v := [64]byte{}
+		if err := dec.Read(v[:]); err != nil {
+			return nil, newError(err)
+		}
+		return v, nil
+	case "public_key":
+		pub := PublicKey{}
+		n, err := pub.Unpack(dec.Remains())
+		if err != nil {
+			return nil, newError(err)
+		}
+		dec.incPos(n)
+		return pub, nil
+	case "signature":
+		sig := Signature{}
+		n, err := sig.Unpack(dec.Remains())
+		if err != nil {
+			return nil, newError(err)
+		}
+		dec.incPos(n)
+		return sig, nil
+	case "symbol":
+		v, err := dec.ReadUint64()
+		if err != nil {
+			return nil, newError(err)
+		}
+		return Symbol{v}, nil
+	case "asset":
+		a := Asset{}
+		amount, err := dec.UnpackInt64()
+		if err != nil {
+			return nil, newError(err)
+		}
+		sym, err := dec.ReadUint64()
+		if err != nil {
+			return nil, newError(err)
+		}
+		a.Amount = amount
+		a.Symbol = Symbol{sym}
+		return a, nil
+	case "extended_asset":
+		quantity, err := t.unpackNativeBaseValue(dec, "asset")
+		if err != nil {
+			return nil, err
+		}
+		contract, err := dec.ReadUint64()
+		if err != nil {
+			return nil, newError(err)
+		}
+		return ExtendedAsset{quantity.(Asset), Name{contract}}, nil
+	}
+	//bool, integers, floats, string and symbol_code are unpacked to Go values by unpackAbiStructField
+	return t.unpackAbiStructField(dec, typ)
+}
@@ -0,0 +1,210 @@
+package uuoskit
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testNativeAbi = `{
+	"version": "eosio::abi/1.1",
+	"types": [{"new_type_name": "account_name", "type": "name"}],
+	"structs": [
+		{"name": "point", "base": "", "fields": [{"name": "x", "type": "int32"}, {"name": "y", "type": "int32"}]},
+		{"name": "record", "base": "", "fields": [
+			{"name": "id", "type": "uint64"},
+			{"name": "owner", "type": "account_name"},
+			{"name": "balance", "type": "asset"},
+			{"name": "tags", "type": "string[]"},
+			{"name": "memo", "type": "string?"},
+			{"name": "hash", "type": "checksum256"},
+			{"name": "key", "type": "public_key"},
+			{"name": "pos", "type": "point"},
+			{"name": "shape", "type": "shape"},
+			{"name": "sym", "type": "symbol"},
+			{"name": "created", "type": "time_point_sec"},
+			{"name": "big", "type": "int128"},
+			{"name": "delta", "type": "varint32"},
+			{"name": "ext", "type": "uint8$"}
+		]}
+	],
+	"actions": [{"name": "record", "type": "record", "ricardian_contract": ""}],
+	"variants": [{"name": "shape", "types": ["uint16", "point"]}]
+}`
+
+func TestPackNativeValue(t *testing.T) {
+	assert := assert.New(t)
+
+	ser := NewABISerializer()
+	err := ser.SetContractABI("test", []byte(testNativeAbi))
+	if err != nil {
+		panic(err)
+	}
+
+	hash := [32]byte{}
+	hash[0] = 0xaa
+	pub, err := NewPublicKeyFromString(testPubKeyA)
+	if err != nil {
+		panic(err)
+	}
+	created := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	record := map[string]interface{}{
+		"id":      1,
+		"owner":   NewName("hello"),
+		"balance": NewAsset(10000, NewSymbol("EOS", 4)),
+		"tags":    []string{"a", "b"},
+		"memo":    nil,
+		"hash":    hash,
+		"key":     pub,
+		"pos":     map[string]interface{}{"x": int32(-1), "y": 2},
+		"shape":   []interface{}{"point", map[string]interface{}{"x": 3, "y": 4}},
+		"sym":     NewSymbol("EOS", 4),
+		"created": created,
+		"big":     big.NewInt(-2),
+		"delta":   -3,
+		"ext":     uint8(5),
+	}
+	packed, err := ser.PackAbiValue("test", "record", record)
+	if err != nil {
+		panic(err)
+	}
+
+	//the same bytes as packing json
+	args := `{"id": 1, "owner": "hello", "balance": "1.0000 EOS", "tags": ["a", "b"], "memo": null,
+		"hash": "aa00000000000000000000000000000000000000000000000000000000000000", "key": "` + testPubKeyA + `",
+		"pos": {"x": -1, "y": 2}, "shape": ["point", {"x": 3, "y": 4}], "sym": "4,EOS", "created": "2021-01-01T00:00:00",
+		"big": "-2", "delta": -3, "ext": 5}`
+	packed2, err := ser.PackActionArgs("test", "record", args)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(hex.EncodeToString(packed2), hex.EncodeToString(packed))
+
+	v, err := ser.UnpackAbiValue("test", "record", packed)
+	if err != nil {
+		panic(err)
+	}
+	bigValue := Int128{}
+	for i := range bigValue {
+		bigValue[i] = 0xff
+	}
+	bigValue[0] = 0xfe
+	assert.Equal(map[string]interface{}{
+		"id":      uint64(1),
+		"owner":   NewName("hello"),
+		"balance": *NewAsset(10000, NewSymbol("EOS", 4)),
+		"tags":    []interface{}{"a", "b"},
+		"memo":    nil,
+		"hash":    hash,
+		"key":     *pub,
+		"pos":     map[string]interface{}{"x": int32(-1), "y": int32(2)},
+		"shape":   []interface{}{"point", map[string]interface{}{"x": int32(3), "y": int32(4)}},
+		"sym":     NewSymbol("EOS", 4),
+		"created": TimePointSec{uint32(created.Unix())},
+		"big":     bigValue,
+		"delta":   int32(-3),
+		"ext":     uint8(5),
+	}, v)
+
+	//unpacked values can be packed again
+	packed2, err = ser.PackAbiValue("test", "record", v)
+	assert.Nil(err)
+	assert.Equal(packed, packed2)
+
+	//Go structs are matched by json tags or the snake case of their field names
+	type nativeBase struct {
+		ID uint64
+	}
+	type nativeRecord struct {
+		nativeBase
+		Owner    Name
+		Balance  *Asset
+		Tags     []string
+		Memo     *string
+		Hash     [32]byte
+		Key      *PublicKey
+		Position struct{ X, Y int } `json:"pos"`
+		Shape    []interface{}
+		Sym      Symbol
+		Created  time.Time
+		Big      *big.Int
+		Delta    int
+		Ext      *uint8
+		cache    int
+		Skipped  string `json:"-"`
+	}
+	ext := uint8(5)
+	goRecord := nativeRecord{
+		nativeBase: nativeBase{ID: 1},
+		Owner:      NewName("hello"),
+		Balance:    NewAsset(10000, NewSymbol("EOS", 4)),
+		Tags:       []string{"a", "b"},
+		Hash:       hash,
+		Key:        pub,
+		Shape:      []interface{}{"point", struct{ X, Y int }{3, 4}},
+		Sym:        NewSymbol("EOS", 4),
+		Created:    created,
+		Big:        big.NewInt(-2),
+		Delta:      -3,
+		Ext:        &ext,
+	}
+	goRecord.Position.X = -1
+	goRecord.Position.Y = 2
+	packed2, err = ser.PackAbiValue("test", "record", &goRecord)
+	assert.Nil(err)
+	assert.Equal(packed, packed2)
+	//nil pointers leave binary extensions out
+	goRecord.Ext = nil
+	packed2, err = ser.PackAbiValue("test", "record", goRecord)
+	assert.Nil(err)
+	assert.Equal(packed[:len(packed)-1], packed2)
+	assert.Equal("owner_id", snakeCase("OwnerID"))
+	assert.Equal("http_server2", snakeCase("HTTPServer2"))
+
+	//strings are parsed like json values
+	record["balance"] = "1.0000 EOS"
+	record["owner"] = "hello"
+	packed2, err = ser.PackAbiValue("test", "record", record)
+	assert.Nil(err)
+	assert.Equal(packed, packed2)
+
+	//binary extensions can be left out
+	delete(record, "ext")
+	packed2, err = ser.PackAbiValue("test", "record", record)
+	assert.Nil(err)
+	assert.Equal(packed[:len(packed)-1], packed2)
+	v, err = ser.UnpackAbiValue("test", "record", packed2)
+	assert.Nil(err)
+	assert.NotContains(v, "ext")
+
+	record["id"] = -1
+	_, err = ser.PackAbiValue("test", "record", record)
+	assert.Contains(err.Error(), "uint64 overflow: -1")
+
+	record["id"] = 1
+	record["pos"] = map[string]interface{}{"x": int64(1) << 31, "y": 2}
+	_, err = ser.PackAbiValue("test", "record", record)
+	assert.Contains(err.Error(), "int32 overflow: 2147483648")
+
+	record["pos"] = map[string]interface{}{"x": 1}
+	_, err = ser.PackAbiValue("test", "record", record)
+	assert.Contains(err.Error(), "missing field y")
+
+	record["pos"] = map[string]interface{}{"x": 1, "y": 2}
+	record["hash"] = hash[:20]
+	_, err = ser.PackAbiValue("test", "record", record)
+	assert.Contains(err.Error(), "invalid checksum256 value: []uint8")
+
+	_, err = ser.PackAbiValue("nobody", "record", record)
+	assert.NotNil(err)
+
+	//huge array lengths do not allocate more than the remaining bytes
+	_, err = (&ABI{}).UnpackAbiValue("uint64[]", []byte{0xff, 0xff, 0xff, 0xff, 0x0f})
+	assert.NotNil(err)
+	_, err = ser.UnpackAbiValue("test", "string[]", []byte{0xff, 0xff, 0xff, 0xff, 0x0f})
+	assert.NotNil(err)
+}
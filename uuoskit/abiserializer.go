@@ -2,6 +2,8 @@ package uuoskit
 
 import (
 	"encoding/json"
+	"sort"
+	"strings"
 
 	"github.com/iancoleman/orderedmap"
 )
@@ -9,6 +11,7 @@ import (
 type ABISerializer struct {
 	contractAbiMap map[string]*ABI
 	contractName   string
+	strict         bool
 }
 
 func NewABISerializer() *ABISerializer {
@@ -31,10 +34,24 @@ func (t *ABISerializer) SetContractABI(contractName string, abi []byte) error {
 		return newError(err)
 	}
 
+	if err := abiObj.Validate(); err != nil {
+		return err
+	}
+	abiObj.getCache()
+	abiObj.SetStrictMode(t.strict)
+
 	t.contractAbiMap[contractName] = abiObj
 	return nil
 }
 
+// SetStrictMode sets the strict mode of all abis, see ABI.SetStrictMode
+func (t *ABISerializer) SetStrictMode(strict bool) {
+	t.strict = strict
+	for _, abi := range t.contractAbiMap {
+		abi.SetStrictMode(strict)
+	}
+}
+
 func (t *ABISerializer) IsAbiCached(contractName string) bool {
 	_, ok := t.contractAbiMap[contractName]
 	return ok
@@ -72,6 +89,53 @@ func (t *ABISerializer) UnpackActionArgs(contractName string, actionName string,
 	return bs, nil
 }
 
+// PackAbiValue packs v, made of native Go values, as typ of the abi of contractName, see ABI.PackAbiValue
+func (t *ABISerializer) PackAbiValue(contractName string, typ string, v interface{}) ([]byte, error) {
+	abi, ok := t.contractAbiMap[contractName]
+	if !ok {
+		return nil, newErrorf("contract not found %s", contractName)
+	}
+	enc := NewEncoder(64)
+	if err := abi.PackAbiValue(enc, typ, v); err != nil {
+		return nil, err
+	}
+	return enc.GetBytes(), nil
+}
+
+// UnpackAbiValue unpacks packedValue as typ of the abi of contractName to native Go values, see ABI.UnpackAbiValue
+func (t *ABISerializer) UnpackAbiValue(contractName string, typ string, packedValue []byte) (interface{}, error) {
+	abi, ok := t.contractAbiMap[contractName]
+	if !ok {
+		return nil, newErrorf("contract not found %s", contractName)
+	}
+	return abi.UnpackAbiValue(typ, packedValue)
+}
+
+// UnpackActionResult decodes the return value of contractName::actionName,
+// e.g. the return_value_hex_data of an action trace, with the result type in action_results of the abi
+func (t *ABISerializer) UnpackActionResult(contractName string, actionName string, packedValue []byte) ([]byte, error) {
+	abi, ok := t.contractAbiMap[contractName]
+	if !ok {
+		return nil, newErrorf("contract not found %s", contractName)
+	}
+
+	resultType := abi.GetActionResultType(actionName)
+	if resultType == "" {
+		return nil, newErrorf("action result type of %s::%s not found", contractName, actionName)
+	}
+
+	dec := NewDecoder(packedValue)
+	result, err := abi.unpackAbiValue(dec, resultType)
+	if err != nil {
+		return nil, newError(err)
+	}
+	bs, err := json.Marshal(result)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return bs, nil
+}
+
 func (t *ABISerializer) PackAbiType(contractName, abiType string, args string) ([]byte, error) {
 	abi, ok := t.contractAbiMap[contractName]
 	if !ok {
@@ -88,6 +152,100 @@ func (t *ABISerializer) UnpackAbiType(contractName, abiName string, packedValue
 	return abi.UnpackAbiType(abiName, packedValue)
 }
 
+// checkAbiVersion accepts any eosio::abi/1.x, as nodeos does
+func checkAbiVersion(version string) error {
+	if !strings.HasPrefix(version, "eosio::abi/1.") {
+		return newErrorf("unsupported abi version: %s", version)
+	}
+	return nil
+}
+
+// sortedNames sorts names in the order of their uint64 values, which is the order of fc::map<name, T>
+func sortedNames(names []string) []string {
+	sort.Slice(names, func(i, j int) bool {
+		return NewName(names[i]).N < NewName(names[j]).N
+	})
+	return names
+}
+
+func packKvTables(enc *Encoder, tables map[string]ABIKvTable) {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+
+	enc.PackVarUint32(uint32(len(names)))
+	for _, name := range sortedNames(names) {
+		table := tables[name]
+		enc.PackName(NewName(name))
+		enc.PackString(table.Type)
+		enc.PackName(NewName(table.PrimaryIndex.Name))
+		enc.PackString(table.PrimaryIndex.Type)
+
+		indices := make([]string, 0, len(table.SecondaryIndices))
+		for index := range table.SecondaryIndices {
+			indices = append(indices, index)
+		}
+		enc.PackVarUint32(uint32(len(indices)))
+		for _, index := range sortedNames(indices) {
+			enc.PackName(NewName(index))
+			enc.PackString(table.SecondaryIndices[index].Type)
+		}
+	}
+}
+
+func unpackKvTables(dec *Decoder) (map[string]ABIKvTable, error) {
+	tables := make(map[string]ABIKvTable)
+	length, err := dec.UnpackVarUint32()
+	if err != nil {
+		return nil, err
+	}
+
+	for ; length > 0; length -= 1 {
+		name, err := dec.UnpackName()
+		if err != nil {
+			return nil, err
+		}
+
+		table := ABIKvTable{SecondaryIndices: make(map[string]ABIKvSecondaryIndex)}
+		table.Type, err = dec.UnpackString()
+		if err != nil {
+			return nil, err
+		}
+
+		primary, err := dec.UnpackName()
+		if err != nil {
+			return nil, err
+		}
+		table.PrimaryIndex.Name = primary.String()
+		table.PrimaryIndex.Type, err = dec.UnpackString()
+		if err != nil {
+			return nil, err
+		}
+
+		length2, err := dec.UnpackVarUint32()
+		if err != nil {
+			return nil, err
+		}
+		for ; length2 > 0; length2 -= 1 {
+			index, err := dec.UnpackName()
+			if err != nil {
+				return nil, err
+			}
+			typ, err := dec.UnpackString()
+			if err != nil {
+				return nil, err
+			}
+			table.SecondaryIndices[index.String()] = ABIKvSecondaryIndex{Type: typ}
+		}
+		tables[name.String()] = table
+	}
+	return tables, nil
+}
+
+// PackABI packs an abi of version eosio::abi/1.x. variants, action_results and kv_tables
+// are binary extensions, they are packed if they are not empty, if they appear in strABI or if a later
+// one is packed, so that an abi returned by UnpackABI is packed to the same bytes
 func (t *ABISerializer) PackABI(strABI string) ([]byte, error) {
 	abi := &ABI{}
 	err := json.Unmarshal([]byte(strABI), abi)
@@ -95,6 +253,22 @@ func (t *ABISerializer) PackABI(strABI string) ([]byte, error) {
 		return nil, newError(err)
 	}
 
+	if err := checkAbiVersion(abi.Version); err != nil {
+		return nil, err
+	}
+
+	sections := make(map[string]json.RawMessage)
+	if err := json.Unmarshal([]byte(strABI), &sections); err != nil {
+		return nil, newError(err)
+	}
+
+	_, hasKvTables := sections["kv_tables"]
+	withKvTables := hasKvTables || len(abi.KvTables) > 0
+	_, hasActionResults := sections["action_results"]
+	withActionResults := withKvTables || hasActionResults || len(abi.ActionResults) > 0
+	_, hasVariants := sections["variants"]
+	withVariants := withActionResults || hasVariants || len(abi.Variants) > 0
+
 	enc := NewEncoder(len(strABI) * 3)
 	enc.PackString(abi.Version)
 	enc.PackVarUint32(uint32(len(abi.Types)))
@@ -164,6 +338,10 @@ func (t *ABISerializer) PackABI(strABI string) ([]byte, error) {
 		enc.PackBytes(a.Extension)
 	}
 
+	if !withVariants {
+		return enc.Bytes(), nil
+	}
+
 	enc.PackVarUint32(uint32(len(abi.Variants)))
 	for i := range abi.Variants {
 		a := &abi.Variants[i]
@@ -175,9 +353,34 @@ func (t *ABISerializer) PackABI(strABI string) ([]byte, error) {
 		}
 	}
 
+	if !withActionResults {
+		return enc.Bytes(), nil
+	}
+
+	enc.PackVarUint32(uint32(len(abi.ActionResults)))
+	for i := range abi.ActionResults {
+		a := &abi.ActionResults[i]
+		enc.PackName(NewName(a.Name))
+		enc.PackString(a.ResultType)
+	}
+
+	if withKvTables {
+		packKvTables(enc, abi.KvTables)
+	}
+
 	return enc.Bytes(), nil
 }
 
+// unpackedABI renders the binary extensions of an abi only if they exist in the binary abi
+type unpackedABI struct {
+	*ABI
+	Variants      *[]VariantDef          `json:"variants,omitempty"`
+	ActionResults *[]ABIActionResult     `json:"action_results,omitempty"`
+	KvTables      *map[string]ABIKvTable `json:"kv_tables,omitempty"`
+}
+
+// UnpackABI unpacks a binary abi of version eosio::abi/1.x to json,
+// variants, action_results and kv_tables are only rendered if they exist in rawAbi
 func (t *ABISerializer) UnpackABI(rawAbi []byte) (string, error) {
 	dec := NewDecoder(rawAbi)
 	abi := &ABI{}
@@ -370,6 +573,12 @@ func (t *ABISerializer) UnpackABI(rawAbi []byte) (string, error) {
 		abi.AbiExtensions = append(abi.AbiExtensions, a)
 	}
 
+	result := &unpackedABI{ABI: abi}
+	if dec.IsEnd() {
+		return marshalUnpackedABI(result)
+	}
+	result.Variants = &abi.Variants
+
 	length, err = dec.UnpackVarUint32()
 	if err != nil {
 		return "", err
@@ -397,6 +606,49 @@ func (t *ABISerializer) UnpackABI(rawAbi []byte) (string, error) {
 		abi.Variants = append(abi.Variants, v)
 	}
 
+	if dec.IsEnd() {
+		return marshalUnpackedABI(result)
+	}
+
+	abi.ActionResults = []ABIActionResult{}
+	result.ActionResults = &abi.ActionResults
+	length, err = dec.UnpackVarUint32()
+	if err != nil {
+		return "", err
+	}
+
+	for ; length > 0; length -= 1 {
+		r := ABIActionResult{}
+		name, err := dec.UnpackName()
+		if err != nil {
+			return "", err
+		}
+		r.Name = name.String()
+
+		r.ResultType, err = dec.UnpackString()
+		if err != nil {
+			return "", err
+		}
+		abi.ActionResults = append(abi.ActionResults, r)
+	}
+
+	if dec.IsEnd() {
+		return marshalUnpackedABI(result)
+	}
+
+	abi.KvTables, err = unpackKvTables(dec)
+	if err != nil {
+		return "", err
+	}
+	result.KvTables = &abi.KvTables
+
+	if !dec.IsEnd() {
+		return "", newErrorf("invalid abi: %d bytes left", len(dec.Remains()))
+	}
+	return marshalUnpackedABI(result)
+}
+
+func marshalUnpackedABI(abi *unpackedABI) (string, error) {
 	ret, err := json.Marshal(abi)
 	if err != nil {
 		return "", err
@@ -0,0 +1,300 @@
+package uuoskit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ABIValidationError holds all the problems found by ABI.Validate
+type ABIValidationError struct {
+	Problems []string
+}
+
+func (e *ABIValidationError) Error() string {
+	return fmt.Sprintf("invalid abi: %s", strings.Join(e.Problems, "; "))
+}
+
+type abiValidator struct {
+	abi      *ABI
+	typedefs map[string]string
+	structs  map[string]*ABIStruct
+	variants map[string]*VariantDef
+	problems []string
+}
+
+func (v *abiValidator) addProblem(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+// resolveTypedef follows typedefs to the type they define, ok is false for circular typedefs
+func (v *abiValidator) resolveTypedef(typ string) (string, bool) {
+	visited := make(map[string]bool)
+	for {
+		next, isTypedef := v.typedefs[typ]
+		if !isTypedef {
+			return typ, true
+		}
+		if visited[typ] {
+			return typ, false
+		}
+		visited[typ] = true
+		typ = next
+	}
+}
+
+// isValidType checks that typ, with optional and array modifiers, is a built-in type, a struct, a variant or a typedef of them
+func (v *abiValidator) isValidType(typ string) bool {
+	for {
+		kind, elem, _ := parseAbiType(typ)
+		if kind == abiTypeNone {
+			break
+		}
+		typ = elem
+	}
+
+	if _, ok := v.typedefs[typ]; ok {
+		resolved, ok := v.resolveTypedef(typ)
+		if !ok {
+			return false
+		}
+		//typedef of an array or optional type
+		if kind, _, _ := parseAbiType(resolved); resolved != typ && kind != abiTypeNone {
+			return v.isValidType(resolved)
+		}
+		typ = resolved
+	}
+
+	if _, ok := gBaseTypes[typ]; ok {
+		return true
+	}
+	if _, ok := v.structs[typ]; ok {
+		return true
+	}
+	_, ok := v.variants[typ]
+	return ok
+}
+
+func (v *abiValidator) checkName(kind string, name string) {
+	if name == "" || !IsValidName(name) {
+		v.addProblem("%s: invalid name %q", kind, name)
+	}
+}
+
+func (v *abiValidator) collectDefinitions() {
+	defined := make(map[string]string)
+	define := func(kind string, name string) bool {
+		if _, ok := gBaseTypes[name]; ok {
+			v.addProblem("%s %s: redefines a built-in type", kind, name)
+			return false
+		}
+		if other, ok := defined[name]; ok {
+			if other == kind {
+				v.addProblem("%s %s: duplicated", kind, name)
+			} else {
+				v.addProblem("%s %s: already defined as %s", kind, name, other)
+			}
+			return false
+		}
+		defined[name] = kind
+		return true
+	}
+
+	for i := range v.abi.Types {
+		typ := &v.abi.Types[i]
+		if define("type", typ.NewTypeName) {
+			v.typedefs[typ.NewTypeName] = typ.Type
+		}
+	}
+
+	for i := range v.abi.Structs {
+		s := &v.abi.Structs[i]
+		if define("struct", s.Name) {
+			v.structs[s.Name] = s
+		}
+	}
+
+	for i := range v.abi.Variants {
+		variant := &v.abi.Variants[i]
+		if define("variant", variant.Name) {
+			v.variants[variant.Name] = variant
+		}
+	}
+}
+
+func (v *abiValidator) checkTypedefs() {
+	for i := range v.abi.Types {
+		typ := &v.abi.Types[i]
+		if _, ok := v.resolveTypedef(typ.NewTypeName); !ok {
+			v.addProblem("type %s: circular typedef", typ.NewTypeName)
+			continue
+		}
+		if !v.isValidType(typ.Type) {
+			v.addProblem("type %s: unknown type %s", typ.NewTypeName, typ.Type)
+		}
+	}
+}
+
+func (v *abiValidator) checkStructs() {
+	for i := range v.abi.Structs {
+		s := &v.abi.Structs[i]
+		if s.Base != "" {
+			v.checkBase(s)
+		}
+
+		fields := make(map[string]bool)
+		extension := false
+		for _, field := range s.Fields {
+			if fields[field.Name] {
+				v.addProblem("struct %s field %s: duplicated", s.Name, field.Name)
+			}
+			fields[field.Name] = true
+
+			typ := field.Type
+			if strings.HasSuffix(typ, "$") {
+				extension = true
+				typ = strings.TrimSuffix(typ, "$")
+			} else if extension {
+				v.addProblem("struct %s field %s: follows a binary extension field", s.Name, field.Name)
+			}
+
+			if !v.isValidType(typ) {
+				v.addProblem("struct %s field %s: unknown type %s", s.Name, field.Name, field.Type)
+			}
+		}
+	}
+}
+
+func (v *abiValidator) checkBase(s *ABIStruct) {
+	visited := map[string]bool{s.Name: true}
+	for current := s; current.Base != ""; {
+		base, ok := v.resolveTypedef(current.Base)
+		if !ok {
+			v.addProblem("struct %s: circular typedef of base %s", s.Name, current.Base)
+			return
+		}
+
+		next, ok := v.structs[base]
+		if !ok {
+			if current == s {
+				v.addProblem("struct %s: base %s is not a struct", s.Name, s.Base)
+			}
+			return
+		}
+
+		if visited[next.Name] {
+			v.addProblem("struct %s: circular base %s", s.Name, s.Base)
+			return
+		}
+		visited[next.Name] = true
+		current = next
+	}
+}
+
+func (v *abiValidator) checkVariants() {
+	for i := range v.abi.Variants {
+		variant := &v.abi.Variants[i]
+		for _, typ := range variant.Types {
+			if !v.isValidType(typ) {
+				v.addProblem("variant %s: unknown type %s", variant.Name, typ)
+			}
+		}
+	}
+}
+
+func (v *abiValidator) checkActionsAndTables() {
+	actions := make(map[string]bool)
+	for i := range v.abi.Actions {
+		action := &v.abi.Actions[i]
+		v.checkName("action", action.Name)
+		if actions[action.Name] {
+			v.addProblem("action %s: duplicated", action.Name)
+		}
+		actions[action.Name] = true
+
+		if !v.isValidType(action.Type) {
+			v.addProblem("action %s: unknown type %s", action.Name, action.Type)
+		}
+	}
+
+	tables := make(map[string]bool)
+	for i := range v.abi.Tables {
+		table := &v.abi.Tables[i]
+		v.checkName("table", table.Name)
+		if tables[table.Name] {
+			v.addProblem("table %s: duplicated", table.Name)
+		}
+		tables[table.Name] = true
+
+		if !v.isValidType(table.Type) {
+			v.addProblem("table %s: unknown type %s", table.Name, table.Type)
+		}
+	}
+
+	results := make(map[string]bool)
+	for i := range v.abi.ActionResults {
+		result := &v.abi.ActionResults[i]
+		v.checkName("action result", result.Name)
+		if results[result.Name] {
+			v.addProblem("action result %s: duplicated", result.Name)
+		}
+		results[result.Name] = true
+
+		if !v.isValidType(result.ResultType) {
+			v.addProblem("action result %s: unknown type %s", result.Name, result.ResultType)
+		}
+	}
+
+	names := make([]string, 0, len(v.abi.KvTables))
+	for name := range v.abi.KvTables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		table := v.abi.KvTables[name]
+		v.checkName("kv table", name)
+		if !v.isValidType(table.Type) {
+			v.addProblem("kv table %s: unknown type %s", name, table.Type)
+		}
+
+		v.checkName(fmt.Sprintf("kv table %s primary index", name), table.PrimaryIndex.Name)
+		if !v.isValidType(table.PrimaryIndex.Type) {
+			v.addProblem("kv table %s primary index: unknown type %s", name, table.PrimaryIndex.Type)
+		}
+
+		indices := make([]string, 0, len(table.SecondaryIndices))
+		for index := range table.SecondaryIndices {
+			indices = append(indices, index)
+		}
+		sort.Strings(indices)
+		for _, index := range indices {
+			v.checkName(fmt.Sprintf("kv table %s secondary index", name), index)
+			if typ := table.SecondaryIndices[index].Type; !v.isValidType(typ) {
+				v.addProblem("kv table %s secondary index %s: unknown type %s", name, index, typ)
+			}
+		}
+	}
+}
+
+// Validate checks that all the types used by the abi can be resolved, that typedefs and struct bases
+// are not circular and that names are unique and valid. All the problems found are returned
+// in an *ABIValidationError
+func (t *ABI) Validate() error {
+	v := &abiValidator{
+		abi:      t,
+		typedefs: make(map[string]string),
+		structs:  make(map[string]*ABIStruct),
+		variants: make(map[string]*VariantDef),
+	}
+
+	v.collectDefinitions()
+	v.checkTypedefs()
+	v.checkStructs()
+	v.checkVariants()
+	v.checkActionsAndTables()
+
+	if len(v.problems) > 0 {
+		return &ABIValidationError{Problems: v.problems}
+	}
+	return nil
+}
@@ -35,6 +35,15 @@ type Action struct {
 }
 
 func NewAction(account Name, name Name, args ...interface{}) *Action {
+	a, err := NewActionE(account, name, args...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return a
+}
+
+// NewActionE returns the errors of packing args into the action data
+func NewActionE(account Name, name Name, args ...interface{}) (*Action, error) {
 	a := &Action{}
 	a.Account = account
 	a.Name = name
@@ -43,7 +52,7 @@ func NewAction(account Name, name Name, args ...interface{}) *Action {
 		if perm, ok := args[0].([]PermissionLevel); ok {
 			a.Authorization = perm
 		} else {
-			panic("third argument not a []PermissionLevel type")
+			return nil, newErrorf("third argument not a []PermissionLevel type")
 		}
 	}
 
@@ -53,17 +62,19 @@ func NewAction(account Name, name Name, args ...interface{}) *Action {
 		for _, v := range args {
 			n, err := CalcPackedSize(v)
 			if err != nil {
-				panic(err.Error())
+				return nil, err
 			}
 			size += n
 		}
 		enc := NewEncoder(size)
 		for _, arg := range args {
-			enc.Pack(arg)
+			if err := enc.Pack(arg); err != nil {
+				return nil, err
+			}
 		}
 		a.Data = enc.GetBytes()
 	}
-	return a
+	return a, nil
 }
 
 func PackUint64(n uint64) []byte {
@@ -0,0 +1,313 @@
+package uuoskit
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultMaxAuthorityDepth is the default max_authority_depth of nodeos
+const DefaultMaxAuthorityDepth = 6
+
+type KeyWeight struct {
+	Key    string `json:"key"`
+	Weight uint16 `json:"weight"`
+}
+
+type PermissionLevelWeight struct {
+	Permission PermissionLevel `json:"permission"`
+	Weight     uint16          `json:"weight"`
+}
+
+type WaitWeight struct {
+	WaitSec uint32 `json:"wait_sec"`
+	Weight  uint16 `json:"weight"`
+}
+
+type Authority struct {
+	Threshold uint32                  `json:"threshold"`
+	Keys      []KeyWeight             `json:"keys"`
+	Accounts  []PermissionLevelWeight `json:"accounts"`
+	Waits     []WaitWeight            `json:"waits"`
+}
+
+// LinkedAction is an action linked to a permission with linkauth, an empty Action links all actions of Account
+type LinkedAction struct {
+	Account Name `json:"account"`
+	Action  Name `json:"action"`
+}
+
+type Permission struct {
+	PermName      Name           `json:"perm_name"`
+	Parent        Name           `json:"parent"`
+	RequiredAuth  Authority      `json:"required_auth"`
+	LinkedActions []LinkedAction `json:"linked_actions"`
+}
+
+// Account holds the permissions part of the /v1/chain/get_account result
+type Account struct {
+	AccountName Name         `json:"account_name"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// NewAccountFromJson parses the result of /v1/chain/get_account
+func NewAccountFromJson(data []byte) (*Account, error) {
+	account := &Account{}
+	if err := json.Unmarshal(data, account); err != nil {
+		return nil, newError(err)
+	}
+
+	if account.AccountName.N == 0 {
+		return nil, newErrorf("invalid account: %s", string(data))
+	}
+	return account, nil
+}
+
+func (a *Account) GetPermission(name Name) *Permission {
+	for i := range a.Permissions {
+		if a.Permissions[i].PermName == name {
+			return &a.Permissions[i]
+		}
+	}
+	return nil
+}
+
+// AuthorityResolution is the result of AuthorityResolver.Resolve
+type AuthorityResolution struct {
+	//keys to sign with, in the format they were passed as available keys
+	RequiredKeys []string `json:"required_keys"`
+	//explanations of the authorizations that can not be satisfied
+	Unsatisfied []string `json:"unsatisfied"`
+}
+
+// AuthorityResolver computes the keys required by the authorizations of actions
+// from the permissions of accounts, without calling /v1/chain/get_required_keys
+type AuthorityResolver struct {
+	accounts map[uint64]*Account
+	loader   func(account string) (*Account, error)
+	maxDepth int
+}
+
+func NewAuthorityResolver() *AuthorityResolver {
+	return &AuthorityResolver{
+		accounts: make(map[uint64]*Account),
+		maxDepth: DefaultMaxAuthorityDepth,
+	}
+}
+
+// AddAccount adds or replaces the permissions of an account
+func (r *AuthorityResolver) AddAccount(account *Account) {
+	r.accounts[account.AccountName.N] = account
+}
+
+// SetAccountLoader sets the function used to load accounts that have not been added
+func (r *AuthorityResolver) SetAccountLoader(loader func(account string) (*Account, error)) {
+	r.loader = loader
+}
+
+func (r *AuthorityResolver) SetMaxDepth(depth int) {
+	r.maxDepth = depth
+}
+
+func (r *AuthorityResolver) getAccount(name Name) (*Account, error) {
+	if account, ok := r.accounts[name.N]; ok {
+		return account, nil
+	}
+
+	if r.loader == nil {
+		return nil, newErrorf("account %s not found", name.String())
+	}
+
+	account, err := r.loader(name.String())
+	if err != nil {
+		return nil, err
+	}
+	r.accounts[name.N] = account
+	return account, nil
+}
+
+// minPermission returns the permission linked to code::action, active if there is no linked permission
+func (r *AuthorityResolver) minPermission(account *Account, code Name, action Name) Name {
+	var linked *Name
+	for i := range account.Permissions {
+		perm := &account.Permissions[i]
+		for _, link := range perm.LinkedActions {
+			if link.Account != code {
+				continue
+			}
+			if link.Action == action {
+				return perm.PermName
+			}
+			if link.Action.N == 0 && linked == nil {
+				linked = &perm.PermName
+			}
+		}
+	}
+
+	if linked != nil {
+		return *linked
+	}
+	return NewName("active")
+}
+
+// satisfies checks that perm is minPerm or one of its parents
+func (r *AuthorityResolver) satisfies(account *Account, perm Name, minPerm Name) bool {
+	if minPerm == NewName("eosio.any") {
+		return true
+	}
+
+	for depth := 0; depth <= len(account.Permissions); depth++ {
+		if perm == minPerm {
+			return true
+		}
+
+		p := account.GetPermission(minPerm)
+		if p == nil || p.Parent.N == 0 {
+			return false
+		}
+		minPerm = p.Parent
+	}
+	return false
+}
+
+type authorityCandidate struct {
+	weight uint16
+	keys   []string
+}
+
+// resolvePermission returns the keys that satisfy level, or an explanation of why it can not be satisfied
+func (r *AuthorityResolver) resolvePermission(level PermissionLevel, availableKeys map[string]string, delaySec uint32, depth int) ([]string, string, error) {
+	levelName := fmt.Sprintf("%s@%s", level.Actor.String(), level.Permission.String())
+	if depth > r.maxDepth {
+		return nil, fmt.Sprintf("%s: exceeds max authority depth %d", levelName, r.maxDepth), nil
+	}
+
+	account, err := r.getAccount(level.Actor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	perm := account.GetPermission(level.Permission)
+	if perm == nil {
+		return nil, fmt.Sprintf("%s: permission does not exist", levelName), nil
+	}
+
+	auth := &perm.RequiredAuth
+	candidates := make([]authorityCandidate, 0, len(auth.Keys)+len(auth.Accounts))
+	for _, kw := range auth.Keys {
+		pub, err := NewPublicKeyFromString(kw.Key)
+		if err != nil {
+			continue
+		}
+		if key, ok := availableKeys[pub.StringEOS()]; ok {
+			candidates = append(candidates, authorityCandidate{kw.Weight, []string{key}})
+		}
+	}
+
+	reasons := []string{}
+	for _, aw := range auth.Accounts {
+		keys, reason, err := r.resolvePermission(aw.Permission, availableKeys, delaySec, depth+1)
+		if err != nil {
+			return nil, "", err
+		}
+		if reason != "" {
+			reasons = append(reasons, reason)
+			continue
+		}
+		candidates = append(candidates, authorityCandidate{aw.Weight, keys})
+	}
+
+	var weight uint32
+	for _, ww := range auth.Waits {
+		if delaySec >= ww.WaitSec {
+			weight += uint32(ww.Weight)
+		}
+	}
+
+	//use heavier keys and accounts first to keep the number of signatures small
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].weight > candidates[j].weight
+	})
+
+	keys := []string{}
+	for _, c := range candidates {
+		if weight >= auth.Threshold {
+			break
+		}
+		weight += uint32(c.weight)
+		keys = append(keys, c.keys...)
+	}
+
+	if weight < auth.Threshold {
+		reason := fmt.Sprintf("%s: weight %d of threshold %d", levelName, weight, auth.Threshold)
+		if len(reasons) > 0 {
+			reason += " (" + strings.Join(reasons, "; ") + ")"
+		}
+		return nil, reason, nil
+	}
+	return keys, "", nil
+}
+
+// Resolve computes the available keys required by the authorizations of actions,
+// delaySec is the delay of the transaction which satisfies wait weights
+func (r *AuthorityResolver) Resolve(actions []Action, availableKeys []string, delaySec uint32) (*AuthorityResolution, error) {
+	keys := make(map[string]string, len(availableKeys))
+	for _, key := range availableKeys {
+		pub, err := NewPublicKeyFromString(key)
+		if err != nil {
+			return nil, err
+		}
+		keys[pub.StringEOS()] = key
+	}
+
+	result := &AuthorityResolution{RequiredKeys: []string{}, Unsatisfied: []string{}}
+	added := make(map[string]bool)
+	for i := range actions {
+		action := &actions[i]
+		for _, level := range action.Authorization {
+			account, err := r.getAccount(level.Actor)
+			if err != nil {
+				return nil, err
+			}
+
+			minPerm := r.minPermission(account, action.Account, action.Name)
+			if !r.satisfies(account, level.Permission, minPerm) {
+				result.Unsatisfied = append(result.Unsatisfied, fmt.Sprintf("%s@%s: does not satisfy %s@%s required by %s::%s",
+					level.Actor.String(), level.Permission.String(), level.Actor.String(), minPerm.String(),
+					action.Account.String(), action.Name.String()))
+				continue
+			}
+
+			required, reason, err := r.resolvePermission(level, keys, delaySec, 0)
+			if err != nil {
+				return nil, err
+			}
+			if reason != "" {
+				result.Unsatisfied = append(result.Unsatisfied, reason)
+				continue
+			}
+
+			for _, key := range required {
+				if !added[key] {
+					added[key] = true
+					result.RequiredKeys = append(result.RequiredKeys, key)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// GetRequiredKeys returns an error explaining the unsatisfied authorizations if actions are under-authorized
+func (r *AuthorityResolver) GetRequiredKeys(actions []Action, availableKeys []string, delaySec uint32) ([]string, error) {
+	result, err := r.Resolve(actions, availableKeys, delaySec)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Unsatisfied) > 0 {
+		return nil, newErrorf("transaction is under-authorized: %s", strings.Join(result.Unsatisfied, ", "))
+	}
+	return result.RequiredKeys, nil
+}
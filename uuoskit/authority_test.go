@@ -0,0 +1,184 @@
+package uuoskit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	secp256k1 "github.com/uuosio/go-secp256k1"
+)
+
+const (
+	testPubKeyA = "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"
+	testPubKeyB = "EOS6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5GDW5CV"
+)
+
+var testAccounts = map[string]string{
+	"hello": `{
+		"account_name": "hello",
+		"permissions": [
+			{"perm_name": "active", "parent": "owner", "required_auth": {"threshold": 1, "keys": [{"key": "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV", "weight": 1}], "accounts": [], "waits": []}},
+			{"perm_name": "owner", "parent": "", "required_auth": {"threshold": 1, "keys": [{"key": "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV", "weight": 1}], "accounts": [], "waits": []}},
+			{"perm_name": "transfer", "parent": "active", "required_auth": {"threshold": 1, "keys": [{"key": "PUB_K1_6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5BoDq63", "weight": 1}], "accounts": [], "waits": []},
+			 "linked_actions": [{"account": "eosio.token", "action": "transfer"}]}
+		]
+	}`,
+	"msig": `{
+		"account_name": "msig",
+		"permissions": [
+			{"perm_name": "active", "parent": "owner", "required_auth": {"threshold": 2, "keys": [{"key": "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV", "weight": 1}, {"key": "EOS6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5GDW5CV", "weight": 1}], "accounts": [], "waits": [{"wait_sec": 3600, "weight": 1}]}}
+		]
+	}`,
+	"team": `{
+		"account_name": "team",
+		"permissions": [
+			{"perm_name": "active", "parent": "owner", "required_auth": {"threshold": 1, "keys": [], "accounts": [{"permission": {"actor": "hello", "permission": "active"}, "weight": 1}], "waits": []}}
+		]
+	}`,
+	"loopa": `{
+		"account_name": "loopa",
+		"permissions": [
+			{"perm_name": "active", "parent": "owner", "required_auth": {"threshold": 1, "keys": [], "accounts": [{"permission": {"actor": "loopb", "permission": "active"}, "weight": 1}], "waits": []}}
+		]
+	}`,
+	"loopb": `{
+		"account_name": "loopb",
+		"permissions": [
+			{"perm_name": "active", "parent": "owner", "required_auth": {"threshold": 1, "keys": [], "accounts": [{"permission": {"actor": "loopa", "permission": "active"}, "weight": 1}], "waits": []}}
+		]
+	}`,
+}
+
+func newTestAuthorityResolver() *AuthorityResolver {
+	resolver := NewAuthorityResolver()
+	for _, account := range testAccounts {
+		a, err := NewAccountFromJson([]byte(account))
+		if err != nil {
+			panic(err)
+		}
+		resolver.AddAccount(a)
+	}
+	return resolver
+}
+
+func newTestAuthAction(code, action, actor, permission string) Action {
+	return *NewAction(NewName(code), NewName(action), []PermissionLevel{{NewName(actor), NewName(permission)}})
+}
+
+func TestAuthorityResolver(t *testing.T) {
+	assert := assert.New(t)
+
+	resolver := newTestAuthorityResolver()
+	allKeys := []string{testPubKeyA, testPubKeyB}
+
+	keys, err := resolver.GetRequiredKeys([]Action{newTestAuthAction("hello", "sayhello", "hello", "active")}, allKeys, 0)
+	assert.Nil(err)
+	assert.Equal([]string{testPubKeyA}, keys)
+
+	//keys are returned in the format they are available in
+	keys, err = resolver.GetRequiredKeys([]Action{newTestAuthAction("eosio.token", "transfer", "hello", "transfer")}, []string{"PUB_K1_6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5BoDq63"}, 0)
+	assert.Nil(err)
+	assert.Equal([]string{"PUB_K1_6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5BoDq63"}, keys)
+
+	//weights and thresholds
+	keys, err = resolver.GetRequiredKeys([]Action{newTestAuthAction("hello", "sayhello", "msig", "active")}, allKeys, 0)
+	assert.Nil(err)
+	assert.Equal(allKeys, keys)
+
+	result, err := resolver.Resolve([]Action{newTestAuthAction("hello", "sayhello", "msig", "active")}, []string{testPubKeyA}, 0)
+	assert.Nil(err)
+	assert.Equal([]string{"msig@active: weight 1 of threshold 2"}, result.Unsatisfied)
+	_, err = resolver.GetRequiredKeys([]Action{newTestAuthAction("hello", "sayhello", "msig", "active")}, []string{testPubKeyA}, 0)
+	assert.Contains(err.Error(), "under-authorized")
+
+	//wait weights are satisfied by the delay of the transaction
+	keys, err = resolver.GetRequiredKeys([]Action{newTestAuthAction("hello", "sayhello", "msig", "active")}, []string{testPubKeyA}, 3600)
+	assert.Nil(err)
+	assert.Equal([]string{testPubKeyA}, keys)
+
+	//nested account authorities
+	keys, err = resolver.GetRequiredKeys([]Action{newTestAuthAction("hello", "sayhello", "team", "active")}, allKeys, 0)
+	assert.Nil(err)
+	assert.Equal([]string{testPubKeyA}, keys)
+
+	//linked permissions
+	keys, err = resolver.GetRequiredKeys([]Action{newTestAuthAction("eosio.token", "transfer", "hello", "transfer")}, allKeys, 0)
+	assert.Nil(err)
+	assert.Equal([]string{testPubKeyB}, keys)
+
+	keys, err = resolver.GetRequiredKeys([]Action{newTestAuthAction("eosio.token", "transfer", "hello", "active")}, allKeys, 0)
+	assert.Nil(err)
+	assert.Equal([]string{testPubKeyA}, keys)
+
+	result, err = resolver.Resolve([]Action{newTestAuthAction("hello", "sayhello", "hello", "transfer")}, allKeys, 0)
+	assert.Nil(err)
+	assert.Equal([]string{"hello@transfer: does not satisfy hello@active required by hello::sayhello"}, result.Unsatisfied)
+
+	//keys used by several actions are only returned once
+	keys, err = resolver.GetRequiredKeys([]Action{
+		newTestAuthAction("hello", "sayhello", "hello", "owner"),
+		newTestAuthAction("eosio.token", "transfer", "hello", "transfer"),
+		newTestAuthAction("hello", "sayhello", "team", "active"),
+	}, allKeys, 0)
+	assert.Nil(err)
+	assert.Equal([]string{testPubKeyA, testPubKeyB}, keys)
+
+	//circular authorities stop at the max authority depth
+	result, err = resolver.Resolve([]Action{newTestAuthAction("hello", "sayhello", "loopa", "active")}, allKeys, 0)
+	assert.Nil(err)
+	assert.Equal(1, len(result.Unsatisfied))
+	assert.Contains(result.Unsatisfied[0], "exceeds max authority depth 6")
+
+	result, err = resolver.Resolve([]Action{newTestAuthAction("hello", "sayhello", "hello", "eosio.code")}, allKeys, 0)
+	assert.Nil(err)
+	assert.Equal([]string{"hello@eosio.code: does not satisfy hello@active required by hello::sayhello"}, result.Unsatisfied)
+
+	_, err = resolver.Resolve([]Action{newTestAuthAction("hello", "sayhello", "nobody", "active")}, allKeys, 0)
+	assert.NotNil(err)
+}
+
+func TestChainApiAuthorityResolver(t *testing.T) {
+	secp256k1.Init()
+	assert := assert.New(t)
+
+	signer := newTestSigner("5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL")
+	loaded := []string{}
+	var pushed PackedTransaction
+	node := newTestNode(t, map[string]func(body []byte) interface{}{
+		"/v1/chain/get_info": func(body []byte) interface{} {
+			return testChainInfo
+		},
+		"/v1/chain/get_account": func(body []byte) interface{} {
+			args := GetAccountArgs{}
+			json.Unmarshal(body, &args)
+			loaded = append(loaded, args.AccountName)
+			return json.RawMessage(testAccounts[args.AccountName])
+		},
+		"/v1/chain/send_transaction": func(body []byte) interface{} {
+			json.Unmarshal(body, &pushed)
+			return map[string]interface{}{"transaction_id": "00"}
+		},
+	})
+	defer node.Close()
+
+	api := NewChainApi(node.URL)
+	api.SetSigner(signer)
+	api.SetAuthorityResolver(api.NewAuthorityResolver())
+
+	action := NewAction(NewName("hello"), NewName("sayhello"), []PermissionLevel{{NewName("team"), NewName("active")}}, "hello")
+	for i := 0; i < 2; i++ {
+		_, err := api.PushAction(action)
+		if err != nil {
+			panic(err)
+		}
+	}
+	//accounts are cached
+	assert.Equal([]string{"team", "hello"}, loaded)
+	assert.Equal([]string{testPubKeyA, testPubKeyA}, signer.signed)
+	assert.Equal(1, len(pushed.Signatures))
+
+	action = NewAction(NewName("hello"), NewName("sayhello"), []PermissionLevel{{NewName("msig"), NewName("active")}}, "hello")
+	_, err := api.PushAction(action)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "msig@active: weight 1 of threshold 2")
+}
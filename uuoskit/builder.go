@@ -0,0 +1,183 @@
+package uuoskit
+
+import (
+	"encoding/hex"
+	"time"
+)
+
+// DefaultExpiration is the expiration used by TransactionBuilder if neither Expiration nor ExpireAt is called
+const DefaultExpiration = 60 * time.Second
+
+// TransactionBuilder builds a PackedTransaction with chained calls, e.g.
+//
+//	NewTransactionBuilder().Expiration(30 * time.Second).BlocksBehind(3).AddAction(action)
+//
+// The first error is kept and returned by Build
+type TransactionBuilder struct {
+	tx              *Transaction
+	contextFreeData []Bytes
+	chainId         string
+	refBlock        string
+	blocksBehind    int
+	expiration      time.Duration
+	expireAt        time.Time
+	err             error
+}
+
+func NewTransactionBuilder() *TransactionBuilder {
+	return &TransactionBuilder{
+		tx:              NewTransaction(0),
+		contextFreeData: []Bytes{},
+		expiration:      DefaultExpiration,
+	}
+}
+
+func (b *TransactionBuilder) setError(err error) *TransactionBuilder {
+	if b.err == nil {
+		b.err = err
+	}
+	return b
+}
+
+// Expiration sets the expiration relative to the head block time, or to the current time if Build is called directly
+func (b *TransactionBuilder) Expiration(expiration time.Duration) *TransactionBuilder {
+	if expiration <= 0 {
+		return b.setError(newErrorf("invalid expiration: %v", expiration))
+	}
+	b.expiration = expiration
+	b.expireAt = time.Time{}
+	return b
+}
+
+// ExpireAt sets an absolute expiration time
+func (b *TransactionBuilder) ExpireAt(t time.Time) *TransactionBuilder {
+	b.expireAt = t
+	return b
+}
+
+func (b *TransactionBuilder) ChainId(chainId string) *TransactionBuilder {
+	if _, err := DecodeHash256(chainId); err != nil {
+		return b.setError(err)
+	}
+	b.chainId = chainId
+	return b
+}
+
+// RefBlock sets the block id used for TAPOS
+func (b *TransactionBuilder) RefBlock(blockId string) *TransactionBuilder {
+	id, err := hex.DecodeString(blockId)
+	if err != nil {
+		return b.setError(newError(err))
+	}
+	if len(id) != 32 {
+		return b.setError(newErrorf("invalid block id: %s", blockId))
+	}
+	b.refBlock = blockId
+	b.blocksBehind = 0
+	return b
+}
+
+// BlocksBehind makes ChainApi use the block n blocks behind the head block for TAPOS
+// instead of the last irreversible block
+func (b *TransactionBuilder) BlocksBehind(n int) *TransactionBuilder {
+	if n <= 0 {
+		return b.setError(newErrorf("invalid blocks behind: %d", n))
+	}
+	b.blocksBehind = n
+	b.refBlock = ""
+	return b
+}
+
+func (b *TransactionBuilder) DelaySec(delaySec uint32) *TransactionBuilder {
+	b.tx.DelaySec = VarUint32(delaySec)
+	return b
+}
+
+func (b *TransactionBuilder) MaxNetUsageWords(words uint32) *TransactionBuilder {
+	b.tx.MaxNetUsageWords = VarUint32(words)
+	return b
+}
+
+func (b *TransactionBuilder) MaxCpuUsageMs(ms uint8) *TransactionBuilder {
+	b.tx.MaxCpuUsageMs = ms
+	return b
+}
+
+func (b *TransactionBuilder) AddAction(action *Action) *TransactionBuilder {
+	b.tx.AddAction(action)
+	return b
+}
+
+func (b *TransactionBuilder) AddActions(actions []*Action) *TransactionBuilder {
+	for _, a := range actions {
+		b.tx.AddAction(a)
+	}
+	return b
+}
+
+func (b *TransactionBuilder) AddContextFreeAction(action *Action) *TransactionBuilder {
+	b.tx.AddContextFreeAction(action)
+	return b
+}
+
+func (b *TransactionBuilder) AddContextFreeData(data []byte) *TransactionBuilder {
+	b.contextFreeData = append(b.contextFreeData, Bytes(data))
+	return b
+}
+
+func (b *TransactionBuilder) AddExtension(ext *TransactionExtension) *TransactionBuilder {
+	if err := b.tx.AddExtension(ext); err != nil {
+		return b.setError(err)
+	}
+	return b
+}
+
+// ResourcePayer makes payer pay for the resources used by the transaction
+func (b *TransactionBuilder) ResourcePayer(payer Name, maxNetBytes uint64, maxCpuUs uint64, maxMemoryBytes uint64) *TransactionBuilder {
+	return b.AddExtension(NewResourcePayerExtension(payer, maxNetBytes, maxCpuUs, maxMemoryBytes))
+}
+
+// Build creates the transaction, RefBlock has to be called before, the chain id is only required for signing.
+// Use ChainApi.BuildTransaction to fill in the reference block, expiration and chain id from the chain
+func (b *TransactionBuilder) Build() (*PackedTransaction, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	if b.refBlock == "" {
+		return nil, newErrorf("reference block is not set")
+	}
+
+	if len(b.tx.Actions) == 0 && len(b.tx.ContextFreeActions) == 0 {
+		return nil, newErrorf("transaction has no actions")
+	}
+
+	expireAt := b.expireAt
+	if expireAt.IsZero() {
+		expireAt = time.Now().Add(b.expiration)
+	}
+
+	//copy tx so that the builder can be reused
+	tx := *b.tx
+	tx.ContextFreeActions = append([]Action{}, b.tx.ContextFreeActions...)
+	tx.Actions = append([]Action{}, b.tx.Actions...)
+	tx.Extention = append([]TransactionExtension{}, b.tx.Extention...)
+	tx.Expiration = TimePointSec{uint32(expireAt.Unix())}
+	if err := tx.SetReferenceBlock(b.refBlock); err != nil {
+		return nil, err
+	}
+
+	packedTx := NewPackedTransaction(&tx)
+	if b.chainId != "" {
+		if err := packedTx.SetChainId(b.chainId); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, data := range b.contextFreeData {
+		if err := packedTx.AddContextFreeData(data); err != nil {
+			return nil, err
+		}
+	}
+	return packedTx, nil
+}
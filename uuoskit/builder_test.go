@@ -0,0 +1,138 @@
+package uuoskit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	secp256k1 "github.com/uuosio/go-secp256k1"
+)
+
+func TestTransactionBuilder(t *testing.T) {
+	assert := assert.New(t)
+
+	refBlock := testChainInfo["last_irreversible_block_id"].(string)
+	expireAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	action := newTestAuthAction("hello", "sayhello", "hello", "active")
+	b := NewTransactionBuilder().
+		ExpireAt(expireAt).
+		RefBlock(refBlock).
+		DelaySec(10).
+		MaxNetUsageWords(100).
+		MaxCpuUsageMs(5).
+		AddAction(&action).
+		AddContextFreeAction(NewAction(NewName("hello"), NewName("cfa"), []PermissionLevel{})).
+		AddContextFreeData([]byte("hello")).
+		ResourcePayer(NewName("payer"), 1, 2, 3)
+
+	packedTx, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+
+	tx := packedTx.tx
+	assert.Equal(uint32(expireAt.Unix()), tx.Expiration.UTCSeconds)
+	assert.Equal(uint16(GetRefBlockNum([]byte{0x00, 0x5a, 0x50, 0xc4})), tx.RefBlockNum)
+	assert.Equal(VarUint32(10), tx.DelaySec)
+	assert.Equal(VarUint32(100), tx.MaxNetUsageWords)
+	assert.Equal(uint8(5), tx.MaxCpuUsageMs)
+	assert.Equal(1, len(tx.Actions))
+	assert.Equal(1, len(tx.ContextFreeActions))
+	assert.Equal([]Bytes{Bytes("hello")}, packedTx.contextFreeData)
+
+	payer, err := tx.GetResourcePayer()
+	assert.Nil(err)
+	assert.Equal(&ResourcePayer{NewName("payer"), 1, 2, 3}, payer)
+
+	//the builder can be reused
+	packedTx2, err := b.AddAction(&action).Build()
+	assert.Nil(err)
+	assert.Equal(2, len(packedTx2.tx.Actions))
+	assert.Equal(1, len(packedTx.tx.Actions))
+
+	//expiration is relative to the current time without a chain
+	packedTx, err = NewTransactionBuilder().Expiration(time.Hour).RefBlock(refBlock).AddAction(&action).Build()
+	assert.Nil(err)
+	assert.InDelta(time.Now().Add(time.Hour).Unix(), int64(packedTx.tx.Expiration.UTCSeconds), 5)
+
+	_, err = NewTransactionBuilder().AddAction(&action).Build()
+	assert.Contains(err.Error(), "reference block is not set")
+
+	_, err = NewTransactionBuilder().RefBlock(refBlock).Build()
+	assert.Contains(err.Error(), "transaction has no actions")
+
+	//the first error is returned
+	_, err = NewTransactionBuilder().RefBlock("00").Expiration(-time.Second).AddAction(&action).Build()
+	assert.Contains(err.Error(), "invalid block id")
+
+	_, err = NewTransactionBuilder().RefBlock(refBlock).BlocksBehind(0).AddAction(&action).Build()
+	assert.Contains(err.Error(), "invalid blocks behind")
+}
+
+func TestChainApiTransactionBuilder(t *testing.T) {
+	secp256k1.Init()
+	assert := assert.New(t)
+
+	signer := newTestSigner("5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL")
+	chainInfo := map[string]interface{}{}
+	for k, v := range testChainInfo {
+		chainInfo[k] = v
+	}
+	chainInfo["head_block_time"] = "2021-01-01T00:00:00.500"
+
+	blockId := "005a50c14ce7a3c4859681ebd3aea624befefaa219c70391fb43ac9453d9cdce"
+	var blockNum float64
+	var requiredKeysArgs map[string]interface{}
+	var pushed PackedTransaction
+	node := newTestNode(t, map[string]func(body []byte) interface{}{
+		"/v1/chain/get_info": func(body []byte) interface{} {
+			return chainInfo
+		},
+		"/v1/chain/get_block_info": func(body []byte) interface{} {
+			args := map[string]interface{}{}
+			json.Unmarshal(body, &args)
+			blockNum = args["block_num"].(float64)
+			return map[string]interface{}{"id": blockId, "block_num": blockNum}
+		},
+		"/v1/chain/get_required_keys": func(body []byte) interface{} {
+			json.Unmarshal(body, &requiredKeysArgs)
+			return map[string]interface{}{"required_keys": []string{testPubKeyA}}
+		},
+		"/v1/chain/send_transaction": func(body []byte) interface{} {
+			json.Unmarshal(body, &pushed)
+			return map[string]interface{}{"transaction_id": "00"}
+		},
+	})
+	defer node.Close()
+
+	api := NewChainApi(node.URL)
+	api.SetSigner(signer)
+
+	action := newTestAuthAction("hello", "sayhello", "hello", "active")
+	b := NewTransactionBuilder().Expiration(30 * time.Second).BlocksBehind(4).DelaySec(5).AddAction(&action)
+	_, err := api.PushTransaction(b)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(float64(5918917-4), blockNum)
+	assert.Equal(1, len(pushed.Signatures))
+	assert.Equal([]string{testPubKeyA}, signer.signed)
+	assert.Equal(float64(5), requiredKeysArgs["transaction"].(map[string]interface{})["delay_sec"])
+
+	tx := &Transaction{}
+	_, err = tx.Unpack(pushed.PackedTx)
+	assert.Nil(err)
+	assert.Equal(uint16(0x50c1), tx.RefBlockNum)
+	assert.Equal(VarUint32(5), tx.DelaySec)
+	assert.Equal(uint32(time.Date(2021, 1, 1, 0, 0, 30, 0, time.UTC).Unix()), tx.Expiration.UTCSeconds)
+
+	//the last irreversible block is used by default
+	packedTx, err := api.BuildTransaction(NewTransactionBuilder().AddAction(&action))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(uint16(0x50c4), packedTx.tx.RefBlockNum)
+	assert.Equal([32]byte{0x9b, 0x16, 0x05, 0xa3, 0xf7, 0xf1, 0x49, 0x95, 0x64, 0x1c, 0x6b, 0x19, 0x41, 0x38, 0x41, 0xc2,
+		0x6c, 0xa8, 0x67, 0x47, 0xf0, 0x54, 0x24, 0x19, 0x51, 0xa2, 0x98, 0xb5, 0x56, 0x16, 0x06, 0x74}, packedTx.chainId)
+}
@@ -48,6 +48,45 @@ func NewChainInfo(info []byte) (*ChainInfo, error) {
 	return chainInfo, nil
 }
 
+// ActionTrace is an action trace of the transaction trace returned by the chain api
+type ActionTrace struct {
+	ActionOrdinal        uint32          `json:"action_ordinal"`
+	CreatorActionOrdinal uint32          `json:"creator_action_ordinal"`
+	Receiver             string          `json:"receiver"`
+	Act                  json.RawMessage `json:"act"`
+	ContextFree          bool            `json:"context_free"`
+	Elapsed              int64           `json:"elapsed"`
+	Console              string          `json:"console"`
+	ReturnValueHexData   string          `json:"return_value_hex_data"`
+	//decoded return value, filled in with the cached abi if the node does not decode it
+	ReturnValueData json.RawMessage `json:"return_value_data,omitempty"`
+	Except          json.RawMessage `json:"except"`
+	ErrorCode       json.RawMessage `json:"error_code"`
+}
+
+// GetAction returns the account and the name of the action
+func (t *ActionTrace) GetAction() (string, string) {
+	act := struct {
+		Account string `json:"account"`
+		Name    string `json:"name"`
+	}{}
+	json.Unmarshal(t.Act, &act)
+	return act.Account, act.Name
+}
+
+// TransactionTrace is the processed field of push_transaction, compute_transaction and send_read_only_transaction
+type TransactionTrace struct {
+	Id           string          `json:"id"`
+	BlockNum     uint32          `json:"block_num"`
+	Elapsed      int64           `json:"elapsed"`
+	NetUsage     uint64          `json:"net_usage"`
+	Scheduled    bool            `json:"scheduled"`
+	ActionTraces []ActionTrace   `json:"action_traces"`
+	Receipt      json.RawMessage `json:"receipt"`
+	Except       json.RawMessage `json:"except"`
+	ErrorCode    json.RawMessage `json:"error_code"`
+}
+
 type ChainContext struct {
 	ABISerializer *ABISerializer
 	PackedTxs     []*PackedTransaction
@@ -1,23 +1,137 @@
 package uuoskit
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"io/ioutil"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// endpoints of the chain api used to push transactions
+const (
+	PushTransactionEndpoint  = "push_transaction"
+	SendTransactionEndpoint  = "send_transaction"
+	SendTransaction2Endpoint = "send_transaction2"
+)
+
 type ChainApi struct {
-	rpc           *Rpc
-	ABISerializer *ABISerializer
+	rpc               *Rpc
+	signer            Signer
+	authorityResolver *AuthorityResolver
+	ABISerializer     *ABISerializer
+	serverVersion     string
+	pushEndpoint      string
+	sendOptions       SendTransactionOptions
+	//push endpoint selected for serverVersion
+	selectedEndpoint string
+	selectedVersion  string
 }
 
 func NewChainApi(rpcUrl string) *ChainApi {
 	rpc := NewRpc(rpcUrl)
 	chainApi := &ChainApi{rpc: rpc, ABISerializer: NewABISerializer()}
+	chainApi.sendOptions.ReturnFailureTrace = true
 	return chainApi
 }
 
+// SelectPushEndpoint returns the newest endpoint for pushing transactions supported by a node
+// with the server_version_string serverVersion, e.g. v2.0.13 or v3.2.4. Antelope Spring numbers its
+// versions from v1.0 again, so a v1.x version can not tell it from EOSIO 1.x and push_transaction
+// is returned, see SelectPushEndpointFromApis
+func SelectPushEndpoint(serverVersion string) string {
+	parts := strings.SplitN(strings.TrimPrefix(serverVersion, "v"), ".", 3)
+	if len(parts) < 2 {
+		return PushTransactionEndpoint
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return PushTransactionEndpoint
+	}
+
+	minor, err := strconv.Atoi(strings.SplitN(parts[1], "-", 2)[0])
+	if err != nil {
+		return PushTransactionEndpoint
+	}
+
+	switch {
+	case major > 3 || (major == 3 && minor >= 1):
+		return SendTransaction2Endpoint
+	case major >= 2:
+		return SendTransactionEndpoint
+	default:
+		return PushTransactionEndpoint
+	}
+}
+
+// SelectPushEndpointFromApis returns the newest endpoint for pushing transactions in apis,
+// the result of /v1/node/get_supported_apis, e.g. /v1/chain/send_transaction2
+func SelectPushEndpointFromApis(apis []string) string {
+	endpoint := PushTransactionEndpoint
+	for _, api := range apis {
+		switch api {
+		case "/v1/chain/" + SendTransaction2Endpoint:
+			return SendTransaction2Endpoint
+		case "/v1/chain/" + SendTransactionEndpoint:
+			endpoint = SendTransactionEndpoint
+		}
+	}
+	return endpoint
+}
+
+// SetPushEndpoint sets the endpoint used to push transactions, one of PushTransactionEndpoint,
+// SendTransactionEndpoint and SendTransaction2Endpoint. By default it is selected from the
+// server_version_string of the node with SelectPushEndpoint, and from the supported apis of v1.x nodes
+func (api *ChainApi) SetPushEndpoint(endpoint string) error {
+	switch endpoint {
+	case "", PushTransactionEndpoint, SendTransactionEndpoint, SendTransaction2Endpoint:
+		api.pushEndpoint = endpoint
+		return nil
+	default:
+		return newErrorf("unknown push endpoint: %s", endpoint)
+	}
+}
+
+// SetSendTransactionOptions sets the options used with send_transaction2,
+// failure traces are returned by default
+func (api *ChainApi) SetSendTransactionOptions(options SendTransactionOptions) {
+	api.sendOptions = options
+}
+
+// SetSigner sets the signer used by PushActions and DeployContract, nil restores the global wallet
+func (api *ChainApi) SetSigner(signer Signer) {
+	api.signer = signer
+}
+
+func (api *ChainApi) GetSigner() Signer {
+	if api.signer == nil {
+		return GetWallet()
+	}
+	return api.signer
+}
+
+// SetAuthorityResolver makes PushActions and DeployContract compute the required keys locally
+// instead of calling /v1/chain/get_required_keys, nil restores the default behavior
+func (api *ChainApi) SetAuthorityResolver(resolver *AuthorityResolver) {
+	api.authorityResolver = resolver
+}
+
+// NewAuthorityResolver creates an AuthorityResolver which loads and caches accounts with get_account
+func (api *ChainApi) NewAuthorityResolver() *AuthorityResolver {
+	resolver := NewAuthorityResolver()
+	resolver.SetAccountLoader(func(account string) (*Account, error) {
+		r, err := api.rpc.Call("chain", "get_account", &GetAccountArgs{AccountName: account})
+		if err != nil {
+			return nil, err
+		}
+		return NewAccountFromJson(r)
+	})
+	return resolver
+}
+
 func (api *ChainApi) GetAccount(name string) (JsonValue, error) {
 	return api.rpc.GetAccount(&GetAccountArgs{AccountName: name})
 }
@@ -67,16 +181,7 @@ func (api *ChainApi) DeployContract(account, codeFile string, abiFile string) er
 		return newError(err)
 	}
 
-	chainInfo, err := api.rpc.GetInfo()
-	if err != nil {
-		return newError(err)
-	}
-
-	expiration := int(time.Now().Unix()) + 60
-	tx := NewTransaction(expiration)
-	tx.SetReferenceBlock(chainInfo.LastIrreversibleBlockID)
-
-	action := NewAction(
+	setCode, err := NewActionE(
 		NewName("eosio"),
 		NewName("setcode"),
 		[]PermissionLevel{{NewName(account), NewName("active")}},
@@ -85,66 +190,132 @@ func (api *ChainApi) DeployContract(account, codeFile string, abiFile string) er
 		uint8(0), //vm_version
 		code,     //code
 	)
-	tx.AddAction(action)
+	if err != nil {
+		return newError(err)
+	}
 
-	action = NewAction(
+	setAbi, err := NewActionE(
 		NewName("eosio"),
 		NewName("setabi"),
 		[]PermissionLevel{{NewName(account), NewName("active")}},
 		NewName(account), //account
 		binABI,           //code
 	)
-	tx.AddAction(action)
-
-	chainId := chainInfo.ChainID
-	packedTx := NewPackedTransaction(tx)
-	packedTx.SetChainId(chainId)
-
-	args := GetRequiredKeysArgs{
-		Transaction:   tx,
-		AvailableKeys: GetWallet().GetPublicKeys(),
-	}
-	r, err := api.rpc.GetRequiredKeys(&args)
 	if err != nil {
 		return newError(err)
 	}
 
-	for i := range r.RequiredKeys {
-		pub := r.RequiredKeys[i]
-		_, err = packedTx.Sign(pub)
-		if err != nil {
-			return newError(err)
-		}
-	}
+	b := NewTransactionBuilder()
+	b.AddAction(setCode)
+	b.AddAction(setAbi)
 
-	r2, err := api.rpc.PushTransaction(packedTx)
+	packedTx, err := api.SignTransaction(b)
 	if err != nil {
 		return newError(err)
 	}
 
-	if _, err := r2.Get("error"); err == nil {
-		if msg, err := r2.Get("error", "details", 0, "message"); err == nil {
+	r2, err := api.PushPackedTransaction(packedTx)
+	if err != nil {
+		if msg, ok := getPushError(r2); ok {
 			log.Println(msg)
 			if msg == "contract is already running this version of code" {
 				return nil
 			}
+			r, err := json.MarshalIndent(r2, "", "  ")
+			if err != nil {
+				panic(err)
+			}
+			return newErrorf("%s", string(r))
 		}
-		r, err := json.MarshalIndent(r, "", "  ")
+		return newError(err)
+	}
+	return nil
+}
+
+// BuildTransaction builds the transaction of b, the chain id, the reference block and the expiration
+// that have not been set are taken from get_info: TAPOS defaults to the last irreversible block
+// and the expiration is relative to the head block time
+func (api *ChainApi) BuildTransaction(b *TransactionBuilder) (*PackedTransaction, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	chainInfo, err := api.rpc.GetInfo()
+	if err != nil {
+		return nil, err
+	}
+	api.serverVersion = chainInfo.ServerVersionString
+
+	resolved := *b
+	if resolved.chainId == "" {
+		resolved.chainId = chainInfo.ChainID
+	}
+
+	if resolved.refBlock == "" {
+		if resolved.blocksBehind > 0 {
+			blockNum := chainInfo.HeadBlockNum - int64(resolved.blocksBehind)
+			if blockNum < 1 {
+				blockNum = 1
+			}
+			resolved.refBlock, err = api.rpc.GetBlockId(blockNum)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			resolved.refBlock = chainInfo.LastIrreversibleBlockID
+		}
+	}
+
+	if resolved.expireAt.IsZero() {
+		resolved.expireAt = time.Now()
+		if t, err := time.Parse("2006-01-02T15:04:05.999", chainInfo.HeadBlockTime); err == nil {
+			resolved.expireAt = t
+		}
+		resolved.expireAt = resolved.expireAt.Add(resolved.expiration)
+	}
+	return resolved.Build()
+}
+
+// SignTransaction builds the transaction of b with BuildTransaction and signs it with the required keys of the signer
+func (api *ChainApi) SignTransaction(b *TransactionBuilder) (*PackedTransaction, error) {
+	packedTx, err := api.BuildTransaction(b)
+	if err != nil {
+		return nil, err
+	}
+
+	signer := api.GetSigner()
+	pubKeys, err := api.getRequiredKeys(signer, packedTx.tx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range pubKeys {
+		pub := pubKeys[i]
+		_, err = packedTx.SignWithSigner(signer, pub)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
-		return newErrorf(string(r))
 	}
-	return nil
+	return packedTx, nil
 }
 
-func (api *ChainApi) getRequiredKeys(actions []Action) ([]string, error) {
+func (api *ChainApi) getRequiredKeys(signer Signer, tx *Transaction) ([]string, error) {
+	availableKeys, err := signer.GetAvailableKeys()
+	if err != nil {
+		return nil, newError(err)
+	}
+
+	if api.authorityResolver != nil {
+		return api.authorityResolver.GetRequiredKeys(tx.Actions, availableKeys, uint32(tx.DelaySec))
+	}
+
 	args := GetRequiredKeysArgs{
 		Transaction:   NewTransaction(0),
-		AvailableKeys: GetWallet().GetPublicKeys(),
+		AvailableKeys: availableKeys,
 	}
-	for i := range actions {
-		a := actions[i]
+	args.Transaction.DelaySec = tx.DelaySec
+	for i := range tx.Actions {
+		a := tx.Actions[i]
 		a.Data = []byte{}
 		args.Transaction.AddAction(&a)
 	}
@@ -174,49 +345,194 @@ func (api *ChainApi) PushAction(action *Action) (JsonValue, error) {
 }
 
 func (api *ChainApi) PushActions(actions []*Action) (JsonValue, error) {
-	chainInfo, err := api.rpc.GetInfo()
+	return api.PushTransaction(NewTransactionBuilder().AddActions(actions))
+}
+
+// PushTransaction signs the transaction of b with SignTransaction and pushes it with PushPackedTransaction
+func (api *ChainApi) PushTransaction(b *TransactionBuilder) (JsonValue, error) {
+	packedTx, err := api.SignTransaction(b)
 	if err != nil {
 		return JsonValue{}, err
 	}
+	return api.PushPackedTransaction(packedTx)
+}
 
-	expiration := int(time.Now().Unix()) + 60
-	tx := NewTransaction(expiration)
-	tx.SetReferenceBlock(chainInfo.LastIrreversibleBlockID)
-	for i := range actions {
-		a := actions[i]
-		tx.AddAction(a)
+// PushPackedTransaction pushes a signed transaction with the endpoint set by SetPushEndpoint,
+// or the newest endpoint supported by the node. The result is returned with the error
+// if the transaction failed, it contains the failure trace with send_transaction2
+func (api *ChainApi) PushPackedTransaction(packedTx *PackedTransaction) (JsonValue, error) {
+	endpoint := api.pushEndpoint
+	if endpoint == "" {
+		var err error
+		endpoint, err = api.selectPushEndpoint()
+		if err != nil {
+			return JsonValue{}, err
+		}
 	}
 
-	chainId := chainInfo.ChainID
-
-	//FIXME: hardcode public key
-	packedTx := NewPackedTransaction(tx)
-	packedTx.SetChainId(chainId)
-
-	pubKeys, err := api.getRequiredKeys(tx.Actions)
+	var r2 JsonValue
+	var err error
+	switch endpoint {
+	case SendTransaction2Endpoint:
+		options := api.sendOptions
+		r2, err = api.rpc.SendTransaction2(packedTx, &options)
+	case SendTransactionEndpoint:
+		r2, err = api.rpc.SendTransaction(packedTx)
+	default:
+		r2, err = api.rpc.PushTransaction(packedTx)
+	}
 	if err != nil {
 		return JsonValue{}, err
 	}
 
-	for i := range pubKeys {
-		pub := pubKeys[i]
-		_, err = packedTx.Sign(pub)
+	if msg, ok := getPushError(r2); ok {
+		return r2, newErrorf("%s", msg)
+	}
+	return r2, nil
+}
+
+// selectPushEndpoint selects the push endpoint with the server version of the node, v1.x nodes
+// are asked for their supported apis to tell Antelope Spring from EOSIO 1.x
+func (api *ChainApi) selectPushEndpoint() (string, error) {
+	if api.serverVersion == "" {
+		chainInfo, err := api.rpc.GetInfo()
 		if err != nil {
-			return JsonValue{}, err
+			return "", err
 		}
+		api.serverVersion = chainInfo.ServerVersionString
+	}
+	if api.selectedEndpoint != "" && api.selectedVersion == api.serverVersion {
+		return api.selectedEndpoint, nil
 	}
 
-	r2, err := api.rpc.PushTransaction(packedTx)
-	if err != nil {
-		return JsonValue{}, err
+	endpoint := SelectPushEndpoint(api.serverVersion)
+	if endpoint == PushTransactionEndpoint && strings.HasPrefix(api.serverVersion, "v1.") {
+		//EOSIO 1.x nodes do not have the endpoint and return an error
+		r, err := api.rpc.Call("node", "get_supported_apis", "")
+		if err != nil {
+			return "", err
+		}
+		result := struct {
+			Apis []string `json:"apis"`
+		}{}
+		if json.Unmarshal(r, &result) == nil {
+			endpoint = SelectPushEndpointFromApis(result.Apis)
+		}
 	}
-	if _, err := r2.Get("error"); err == nil {
-		msg, err := r2.Get("error", "details", 0, "message")
+
+	api.selectedEndpoint = endpoint
+	api.selectedVersion = api.serverVersion
+	return endpoint, nil
+}
+
+// getPushError returns the error message of a failed push, both the error response of the node
+// and the failure trace returned by send_transaction2 are checked
+func getPushError(r JsonValue) (string, bool) {
+	if _, err := r.Get("error"); err == nil {
+		msg, err := r.GetString("error", "details", 0, "message")
 		if err == nil {
-			return r2, newErrorf(msg.(string))
+			return msg, true
 		}
 		log.Println(err)
-		return r2, newErrorf("push_transaction error")
+		return "push_transaction error", true
+	}
+
+	except, err := r.GetTyped("processed", "except")
+	if err != nil {
+		return "", false
+	}
+	if _, ok := except.(map[string]JsonValue); !ok {
+		return "", false
+	}
+
+	if msg, err := r.GetString("processed", "except", "stack", 0, "format"); err == nil {
+		return msg, true
+	}
+	if msg, err := r.GetString("processed", "except", "message"); err == nil {
+		return msg, true
+	}
+	return "transaction failed", true
+}
+
+// ComputeTransaction executes the transaction of b with /v1/chain/compute_transaction without
+// broadcasting it, the transaction is signed if sign is true. The result is returned with the error
+// if the transaction failed
+func (api *ChainApi) ComputeTransaction(b *TransactionBuilder, sign bool) (*TransactionTrace, error) {
+	return api.executeTransaction("compute_transaction", b, sign)
+}
+
+// SendReadOnlyTransaction executes the transaction of b with /v1/chain/send_read_only_transaction,
+// the transaction is signed if sign is true
+func (api *ChainApi) SendReadOnlyTransaction(b *TransactionBuilder, sign bool) (*TransactionTrace, error) {
+	return api.executeTransaction("send_read_only_transaction", b, sign)
+}
+
+func (api *ChainApi) executeTransaction(endpoint string, b *TransactionBuilder, sign bool) (*TransactionTrace, error) {
+	var packedTx *PackedTransaction
+	var err error
+	if sign {
+		packedTx, err = api.SignTransaction(b)
+	} else {
+		packedTx, err = api.BuildTransaction(b)
+		if err == nil {
+			packedTx.packTx()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	args := struct {
+		Transaction *PackedTransaction `json:"transaction"`
+	}{packedTx}
+	r, err := api.rpc.Call("chain", endpoint, &args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := JsonValue{}
+	if err := json.Unmarshal(r, &result); err != nil {
+		return nil, newError(err)
+	}
+
+	processed := struct {
+		Processed *TransactionTrace `json:"processed"`
+	}{}
+	if err := json.Unmarshal(r, &processed); err != nil {
+		return nil, newError(err)
+	}
+
+	trace := processed.Processed
+	if trace != nil {
+		api.decodeReturnValues(trace)
+	}
+
+	if msg, ok := getPushError(result); ok {
+		return trace, newErrorf("%s", msg)
+	}
+
+	if trace == nil {
+		return nil, newErrorf("invalid %s result: %s", endpoint, string(r))
+	}
+	return trace, nil
+}
+
+// decodeReturnValues decodes the return values of actions with the abis cached in ABISerializer
+func (api *ChainApi) decodeReturnValues(trace *TransactionTrace) {
+	for i := range trace.ActionTraces {
+		a := &trace.ActionTraces[i]
+		if a.ReturnValueHexData == "" || (len(a.ReturnValueData) > 0 && string(a.ReturnValueData) != "null") {
+			continue
+		}
+
+		data, err := hex.DecodeString(a.ReturnValueHexData)
+		if err != nil {
+			continue
+		}
+
+		account, action := a.GetAction()
+		if value, err := api.ABISerializer.UnpackActionResult(account, action, data); err == nil {
+			a.ReturnValueData = value
+		}
 	}
-	return r2, nil
 }
@@ -1,6 +1,13 @@
 package uuoskit
 
-import "testing"
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	secp256k1 "github.com/uuosio/go-secp256k1"
+)
 
 func TestChainApi(t *testing.T) {
 	GetWallet().Import("test", "5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL")
@@ -48,3 +55,246 @@ func TestPushAction(t *testing.T) {
 		t.Log(r)
 	}
 }
+
+func TestSelectPushEndpoint(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(PushTransactionEndpoint, SelectPushEndpoint("v1.8.6"))
+	assert.Equal(SendTransactionEndpoint, SelectPushEndpoint("v2.0.13"))
+	assert.Equal(SendTransactionEndpoint, SelectPushEndpoint("v2.1.0-rc1"))
+	assert.Equal(SendTransactionEndpoint, SelectPushEndpoint("v3.0.0"))
+	assert.Equal(SendTransaction2Endpoint, SelectPushEndpoint("v3.1.0"))
+	assert.Equal(SendTransaction2Endpoint, SelectPushEndpoint("v4.0.4"))
+	assert.Equal(PushTransactionEndpoint, SelectPushEndpoint(""))
+	assert.Equal(PushTransactionEndpoint, SelectPushEndpoint("unknown"))
+
+	assert.Equal(SendTransaction2Endpoint, SelectPushEndpointFromApis([]string{"/v1/chain/send_transaction", "/v1/chain/send_transaction2"}))
+	assert.Equal(SendTransactionEndpoint, SelectPushEndpointFromApis([]string{"/v1/chain/push_transaction", "/v1/chain/send_transaction"}))
+	assert.Equal(PushTransactionEndpoint, SelectPushEndpointFromApis(nil))
+}
+
+func TestSelectPushEndpointSpring(t *testing.T) {
+	assert := assert.New(t)
+
+	serverVersion := "v1.0.2"
+	var apis interface{} = map[string]interface{}{
+		"apis": []string{"/v1/chain/get_info", "/v1/chain/send_transaction", "/v1/chain/send_transaction2"},
+	}
+	requests := 0
+	node := newTestNode(t, map[string]func(body []byte) interface{}{
+		"/v1/chain/get_info": func(body []byte) interface{} {
+			return map[string]interface{}{"server_version_string": serverVersion}
+		},
+		"/v1/node/get_supported_apis": func(body []byte) interface{} {
+			requests += 1
+			return apis
+		},
+	})
+	defer node.Close()
+
+	//Antelope Spring 1.x
+	api := NewChainApi(node.URL)
+	endpoint, err := api.selectPushEndpoint()
+	assert.Nil(err)
+	assert.Equal(SendTransaction2Endpoint, endpoint)
+	endpoint, _ = api.selectPushEndpoint()
+	assert.Equal(SendTransaction2Endpoint, endpoint)
+	assert.Equal(1, requests)
+
+	//EOSIO 1.x
+	serverVersion = "v1.8.6"
+	apis = map[string]interface{}{"code": 404, "message": "Not Found", "error": map[string]interface{}{"name": "exception"}}
+	api = NewChainApi(node.URL)
+	endpoint, err = api.selectPushEndpoint()
+	assert.Nil(err)
+	assert.Equal(PushTransactionEndpoint, endpoint)
+
+	//newer versions are selected without asking the node
+	serverVersion = "v3.2.4"
+	api = NewChainApi(node.URL)
+	endpoint, _ = api.selectPushEndpoint()
+	assert.Equal(SendTransaction2Endpoint, endpoint)
+	assert.Equal(2, requests)
+}
+
+func TestSendTransaction2(t *testing.T) {
+	secp256k1.Init()
+	assert := assert.New(t)
+
+	chainInfo := map[string]interface{}{}
+	for k, v := range testChainInfo {
+		chainInfo[k] = v
+	}
+	chainInfo["server_version_string"] = "v3.2.4"
+
+	var args map[string]interface{}
+	var pushed PackedTransaction
+	var result interface{} = map[string]interface{}{"transaction_id": "00", "processed": map[string]interface{}{"except": nil}}
+	node := newTestNode(t, map[string]func(body []byte) interface{}{
+		"/v1/chain/get_info": func(body []byte) interface{} {
+			return chainInfo
+		},
+		"/v1/chain/get_required_keys": func(body []byte) interface{} {
+			return GetRequiredKeysResult{RequiredKeys: []string{testPubKeyA}}
+		},
+		"/v1/chain/send_transaction2": func(body []byte) interface{} {
+			json.Unmarshal(body, &args)
+			raw, _ := json.Marshal(args["transaction"])
+			json.Unmarshal(raw, &pushed)
+			return result
+		},
+	})
+	defer node.Close()
+
+	api := NewChainApi(node.URL)
+	api.SetSigner(newTestSigner("5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL"))
+	action := NewAction(NewName("hello"), NewName("sayhello"), []PermissionLevel{{NewName("hello"), NewName("active")}}, "hello")
+	_, err := api.PushAction(action)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(true, args["return_failure_trace"])
+	assert.Equal(false, args["retry_trx"])
+	assert.Nil(args["retry_trx_num_blocks"])
+	assert.Equal(1, len(pushed.Signatures))
+
+	api.SetSendTransactionOptions(SendTransactionOptions{RetryTrx: true, RetryTrxNumBlocks: 3})
+	_, err = api.PushAction(action)
+	assert.Nil(err)
+	assert.Equal(false, args["return_failure_trace"])
+	assert.Equal(true, args["retry_trx"])
+	assert.Equal(float64(3), args["retry_trx_num_blocks"])
+
+	//failure traces are returned with the error
+	result = map[string]interface{}{
+		"transaction_id": "00",
+		"processed": map[string]interface{}{
+			"except": map[string]interface{}{
+				"code":    3050003,
+				"name":    "eosio_assert_message_exception",
+				"message": "eosio_assert_message assertion failure",
+				"stack":   []interface{}{map[string]interface{}{"format": "assertion failure with message: hello"}},
+			},
+		},
+	}
+	r, err := api.PushAction(action)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "assertion failure with message: hello")
+	name, _ := r.GetString("processed", "except", "name")
+	assert.Equal("eosio_assert_message_exception", name)
+
+	assert.NotNil(api.SetPushEndpoint("push_transactions"))
+}
+
+var testReturnValueAbi = `{
+	"version": "eosio::abi/1.2",
+	"structs": [
+		{"name": "sayhello", "base": "", "fields": [{"name": "name", "type": "string"}]},
+		{"name": "greeting", "base": "", "fields": [{"name": "to", "type": "name"}, {"name": "count", "type": "uint32"}]}
+	],
+	"actions": [
+		{"name": "sayhello", "type": "sayhello", "ricardian_contract": ""},
+		{"name": "greet", "type": "sayhello", "ricardian_contract": ""}
+	],
+	"action_results": [
+		{"name": "sayhello", "result_type": "string"},
+		{"name": "greet", "result_type": "greeting"}
+	]
+}`
+
+func TestComputeTransaction(t *testing.T) {
+	secp256k1.Init()
+	assert := assert.New(t)
+
+	newActionTrace := func(action string, returnValue string) map[string]interface{} {
+		return map[string]interface{}{
+			"action_ordinal":        1,
+			"receiver":              "hello",
+			"act":                   map[string]interface{}{"account": "hello", "name": action, "authorization": []interface{}{}, "data": map[string]interface{}{}},
+			"console":               "hello",
+			"return_value_hex_data": returnValue,
+			"except":                nil,
+		}
+	}
+
+	var args map[string]interface{}
+	var result interface{}
+	endpoints := []string{}
+	handler := func(endpoint string) func(body []byte) interface{} {
+		return func(body []byte) interface{} {
+			endpoints = append(endpoints, endpoint)
+			json.Unmarshal(body, &args)
+			return result
+		}
+	}
+	node := newTestNode(t, map[string]func(body []byte) interface{}{
+		"/v1/chain/get_info": func(body []byte) interface{} {
+			return testChainInfo
+		},
+		"/v1/chain/get_required_keys": func(body []byte) interface{} {
+			return GetRequiredKeysResult{RequiredKeys: []string{testPubKeyA}}
+		},
+		"/v1/chain/compute_transaction":        handler("compute_transaction"),
+		"/v1/chain/send_read_only_transaction": handler("send_read_only_transaction"),
+	})
+	defer node.Close()
+
+	api := NewChainApi(node.URL)
+	api.SetSigner(newTestSigner("5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL"))
+	err := api.ABISerializer.SetContractABI("hello", []byte(testReturnValueAbi))
+	if err != nil {
+		panic(err)
+	}
+
+	helloName := NewName("hello")
+	result = map[string]interface{}{
+		"transaction_id": "00",
+		"processed": map[string]interface{}{
+			"id":      "00",
+			"elapsed": 100,
+			"action_traces": []interface{}{
+				//"hello" as a string
+				newActionTrace("sayhello", "0568656c6c6f"),
+				//{"to": "hello", "count": 3}
+				newActionTrace("greet", hex.EncodeToString(append(helloName.Pack(), 3, 0, 0, 0))),
+				newActionTrace("unknown", "00"),
+			},
+			"except": nil,
+		},
+	}
+
+	action := NewAction(NewName("hello"), NewName("sayhello"), []PermissionLevel{{NewName("hello"), NewName("active")}}, "hello")
+	trace, err := api.ComputeTransaction(NewTransactionBuilder().AddAction(action), false)
+	if err != nil {
+		panic(err)
+	}
+	tx := args["transaction"].(map[string]interface{})
+	assert.Equal([]interface{}{}, tx["signatures"])
+	assert.NotEqual("", tx["packed_trx"])
+
+	assert.Equal(int64(100), trace.Elapsed)
+	assert.Equal(3, len(trace.ActionTraces))
+	assert.Equal(`"hello"`, string(trace.ActionTraces[0].ReturnValueData))
+	assert.Equal(`{"to":"hello","count":3}`, string(trace.ActionTraces[1].ReturnValueData))
+	assert.Nil(trace.ActionTraces[2].ReturnValueData)
+	account, name := trace.ActionTraces[1].GetAction()
+	assert.Equal("hello", account)
+	assert.Equal("greet", name)
+
+	trace, err = api.SendReadOnlyTransaction(NewTransactionBuilder().AddAction(action), true)
+	if err != nil {
+		panic(err)
+	}
+	tx = args["transaction"].(map[string]interface{})
+	assert.Equal(1, len(tx["signatures"].([]interface{})))
+	assert.Equal(`"hello"`, string(trace.ActionTraces[0].ReturnValueData))
+	assert.Equal([]string{"compute_transaction", "send_read_only_transaction"}, endpoints)
+
+	result = map[string]interface{}{
+		"code":    500,
+		"message": "Internal Service Error",
+		"error":   map[string]interface{}{"details": []interface{}{map[string]interface{}{"message": "assertion failure with message: 100%d"}}},
+	}
+	trace, err = api.ComputeTransaction(NewTransactionBuilder().AddAction(action), false)
+	assert.Nil(trace)
+	assert.Contains(err.Error(), "assertion failure with message: 100%d")
+}
@@ -0,0 +1,590 @@
+package uuoskit
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/akamensky/base58"
+	secp256k1 "github.com/uuosio/go-secp256k1"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// KeyType is the variant index of a public key, private key or signature
+// as used by the public_key and signature abi types
+type KeyType uint8
+
+const (
+	KeyTypeK1 KeyType = 0
+	KeyTypeR1 KeyType = 1
+	KeyTypeWA KeyType = 2
+)
+
+func (t KeyType) String() string {
+	switch t {
+	case KeyTypeK1:
+		return "K1"
+	case KeyTypeR1:
+		return "R1"
+	case KeyTypeWA:
+		return "WA"
+	default:
+		return "unknown"
+	}
+}
+
+// PublicKey holds a compressed K1 or R1 public key, for WebAuthn keys Data is
+// the compressed secp256r1 key followed by the user presence flag and the rpid
+type PublicKey struct {
+	Type KeyType
+	Data []byte
+}
+
+// PrivateKey holds a K1 or R1 private key
+type PrivateKey struct {
+	Type KeyType
+	Data [32]byte
+}
+
+// Signature holds a K1 or R1 compact signature:
+// one byte recovery id followed by the r and s values,
+// for WebAuthn signatures Data is the compact signature followed by the authenticator data and the client json
+type Signature struct {
+	Type KeyType
+	Data []byte
+}
+
+var r1CurveHalfOrder = new(big.Int).Rsh(elliptic.P256().Params().N, 1)
+
+func checksumRipemd160(data []byte, suffix string) []byte {
+	hash := ripemd160.New()
+	hash.Write(data)
+	hash.Write([]byte(suffix))
+	return hash.Sum(nil)[:4]
+}
+
+func encodeKeyString(prefix string, keyType KeyType, data []byte) string {
+	buf := make([]byte, 0, len(data)+4)
+	buf = append(buf, data...)
+	buf = append(buf, checksumRipemd160(data, keyType.String())...)
+	return prefix + keyType.String() + "_" + base58.Encode(buf)
+}
+
+// decodeKeyString decodes strings like PUB_K1_xxx, the key type is returned with the key data
+func decodeKeyString(prefix string, s string) (KeyType, []byte, error) {
+	var keyType KeyType
+	if strings.HasPrefix(s, prefix+"K1_") {
+		keyType = KeyTypeK1
+	} else if strings.HasPrefix(s, prefix+"R1_") {
+		keyType = KeyTypeR1
+	} else if strings.HasPrefix(s, prefix+"WA_") {
+		keyType = KeyTypeWA
+	} else {
+		return 0, nil, newErrorf("unknown key format: %s", s)
+	}
+
+	buf, err := base58.Decode(s[len(prefix)+3:])
+	if err != nil {
+		return 0, nil, newError(err)
+	}
+
+	if len(buf) <= 4 {
+		return 0, nil, newErrorf("invalid key length: %s", s)
+	}
+
+	data := buf[:len(buf)-4]
+	if !bytes.Equal(buf[len(buf)-4:], checksumRipemd160(data, keyType.String())) {
+		return 0, nil, newErrorf("checksum mismatch: %s", s)
+	}
+	return keyType, data, nil
+}
+
+// NewPublicKeyFromString parses public keys in the EOS, PUB_K1_ or PUB_R1_ format
+func NewPublicKeyFromString(s string) (*PublicKey, error) {
+	if strings.HasPrefix(s, "EOS") {
+		pub, err := secp256k1.NewPublicKeyFromBase58(s)
+		if err != nil {
+			return nil, newError(err)
+		}
+		return &PublicKey{Type: KeyTypeK1, Data: pub.Data[:]}, nil
+	}
+
+	keyType, data, err := decodeKeyString("PUB_", s)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyType == KeyTypeWA {
+		pub := &PublicKey{Type: keyType}
+		if err := pub.unpackData(NewDecoder(data)); err != nil {
+			return nil, err
+		}
+		if len(pub.Data) != len(data) {
+			return nil, newErrorf("invalid public key length: %s", s)
+		}
+		return pub, nil
+	}
+
+	if len(data) != 33 {
+		return nil, newErrorf("invalid public key length: %s", s)
+	}
+
+	if keyType == KeyTypeR1 {
+		if x, _ := elliptic.UnmarshalCompressed(elliptic.P256(), data); x == nil {
+			return nil, newErrorf("invalid R1 public key: %s", s)
+		}
+	}
+	return &PublicKey{Type: keyType, Data: data}, nil
+}
+
+// String returns the public key in the PUB_K1_ or PUB_R1_ format
+func (pub *PublicKey) String() string {
+	return encodeKeyString("PUB_", pub.Type, pub.Data)
+}
+
+// StringEOS returns K1 public keys in the legacy EOS format, other key types in the format of String
+func (pub *PublicKey) StringEOS() string {
+	if pub.Type != KeyTypeK1 {
+		return pub.String()
+	}
+
+	buf := make([]byte, 0, len(pub.Data)+4)
+	buf = append(buf, pub.Data...)
+	buf = append(buf, checksumRipemd160(pub.Data, "")...)
+	return "EOS" + base58.Encode(buf)
+}
+
+func (pub *PublicKey) Pack() []byte {
+	enc := NewEncoder(pub.Size())
+	enc.PackUint8(uint8(pub.Type))
+	enc.WriteBytes(pub.Data)
+	return enc.GetBytes()
+}
+
+func (pub *PublicKey) Unpack(data []byte) (int, error) {
+	dec := NewDecoder(data)
+	keyType, err := dec.UnpackUint8()
+	if err != nil {
+		return 0, err
+	}
+
+	pub.Type = KeyType(keyType)
+	if err := pub.unpackData(dec); err != nil {
+		return 0, err
+	}
+	return dec.Pos(), nil
+}
+
+func (pub *PublicKey) unpackData(dec *Decoder) error {
+	start := dec.Pos()
+	switch pub.Type {
+	case KeyTypeK1, KeyTypeR1:
+		if err := dec.checkPos(33); err != nil {
+			return err
+		}
+		dec.incPos(33)
+	case KeyTypeWA:
+		if err := dec.checkPos(33); err != nil {
+			return err
+		}
+		dec.incPos(33)
+		if _, err := dec.UnpackUint8(); err != nil {
+			return err
+		}
+		if _, err := dec.UnpackString(); err != nil {
+			return err
+		}
+	default:
+		return newErrorf("unsupported public key type %d", pub.Type)
+	}
+	pub.Data = make([]byte, dec.Pos()-start)
+	copy(pub.Data, dec.buf[start:dec.Pos()])
+	return nil
+}
+
+// UserPresence returns the user presence flag of a WebAuthn public key
+func (pub *PublicKey) UserPresence() uint8 {
+	if pub.Type != KeyTypeWA || len(pub.Data) < 34 {
+		return 0
+	}
+	return pub.Data[33]
+}
+
+// RPID returns the relying party id of a WebAuthn public key
+func (pub *PublicKey) RPID() string {
+	if pub.Type != KeyTypeWA || len(pub.Data) < 34 {
+		return ""
+	}
+	dec := NewDecoder(pub.Data[34:])
+	rpid, _ := dec.UnpackString()
+	return rpid
+}
+
+func (pub *PublicKey) Size() int {
+	return 1 + len(pub.Data)
+}
+
+// NewPrivateKeyFromString parses private keys in the legacy WIF, PVT_K1_ or PVT_R1_ format
+func NewPrivateKeyFromString(s string) (*PrivateKey, error) {
+	if !strings.HasPrefix(s, "PVT_") {
+		priv, err := secp256k1.NewPrivateKeyFromBase58(s)
+		if err != nil {
+			return nil, newError(err)
+		}
+		return &PrivateKey{Type: KeyTypeK1, Data: priv.Data}, nil
+	}
+
+	keyType, data, err := decodeKeyString("PVT_", s)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyType == KeyTypeWA {
+		return nil, newErrorf("WebAuthn private keys are not supported")
+	}
+
+	if len(data) != 32 {
+		return nil, newErrorf("invalid private key length")
+	}
+
+	priv := &PrivateKey{Type: keyType}
+	copy(priv.Data[:], data)
+	return priv, nil
+}
+
+// GeneratePrivateKey creates a random private key of keyType
+func GeneratePrivateKey(keyType KeyType) (*PrivateKey, error) {
+	var curveOrder *big.Int
+	switch keyType {
+	case KeyTypeK1:
+		curveOrder = new(big.Int).SetBytes([]byte{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 254, 186, 174, 220, 230, 175, 72, 160, 59, 191, 210, 94, 140, 208, 54, 65, 65})
+	case KeyTypeR1:
+		curveOrder = elliptic.P256().Params().N
+	default:
+		return nil, newErrorf("unsupported key type %d", keyType)
+	}
+
+	priv := &PrivateKey{Type: keyType}
+	d := new(big.Int)
+	for {
+		if _, err := io.ReadFull(rand.Reader, priv.Data[:]); err != nil {
+			return nil, newError(err)
+		}
+		d.SetBytes(priv.Data[:])
+		if d.Sign() > 0 && d.Cmp(curveOrder) < 0 {
+			return priv, nil
+		}
+	}
+}
+
+// String returns K1 private keys in the legacy WIF format and R1 private keys in the PVT_R1_ format
+func (priv *PrivateKey) String() string {
+	if priv.Type == KeyTypeK1 {
+		return secp256k1.NewPrivateKey(priv.Data[:]).String()
+	}
+	return encodeKeyString("PVT_", priv.Type, priv.Data[:])
+}
+
+func (priv *PrivateKey) toECDSA() *ecdsa.PrivateKey {
+	curve := elliptic.P256()
+	key := &ecdsa.PrivateKey{}
+	key.Curve = curve
+	key.D = new(big.Int).SetBytes(priv.Data[:])
+	key.X, key.Y = curve.ScalarBaseMult(priv.Data[:])
+	return key
+}
+
+func (priv *PrivateKey) GetPublicKey() *PublicKey {
+	if priv.Type == KeyTypeK1 {
+		pub := secp256k1.NewPrivateKey(priv.Data[:]).GetPublicKey()
+		return &PublicKey{Type: KeyTypeK1, Data: pub.Data[:]}
+	}
+
+	key := priv.toECDSA()
+	return &PublicKey{Type: priv.Type, Data: elliptic.MarshalCompressed(key.Curve, key.X, key.Y)}
+}
+
+// Sign signs a 32 bytes digest, the returned signature allows recovering the public key
+func (priv *PrivateKey) Sign(digest []byte) (*Signature, error) {
+	if len(digest) != 32 {
+		return nil, newErrorf("invalid digest length")
+	}
+
+	if priv.Type == KeyTypeK1 {
+		sig, err := secp256k1.NewPrivateKey(priv.Data[:]).Sign(digest)
+		if err != nil {
+			return nil, newError(err)
+		}
+		return &Signature{Type: KeyTypeK1, Data: sig.Data[:]}, nil
+	}
+
+	key := priv.toECDSA()
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+	if err != nil {
+		return nil, newError(err)
+	}
+
+	//only signatures with a low s value are accepted by the chain
+	if s.Cmp(r1CurveHalfOrder) > 0 {
+		s.Sub(key.Curve.Params().N, s)
+	}
+
+	pub := elliptic.MarshalCompressed(key.Curve, key.X, key.Y)
+	data := make([]byte, 65)
+	r.FillBytes(data[1:33])
+	s.FillBytes(data[33:65])
+	for recId := 0; recId < 4; recId++ {
+		data[0] = byte(27 + 4 + recId)
+		sig := &Signature{Type: priv.Type, Data: data}
+		recovered, err := recoverR1(digest, sig.Data)
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(recovered, pub) {
+			return sig, nil
+		}
+	}
+	return nil, newErrorf("unable to find recovery id of signature")
+}
+
+// NewSignatureFromString parses signatures in the SIG_K1_ or SIG_R1_ format
+func NewSignatureFromString(s string) (*Signature, error) {
+	keyType, data, err := decodeKeyString("SIG_", s)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyType == KeyTypeWA {
+		sig := &Signature{Type: keyType}
+		if err := sig.unpackData(NewDecoder(data)); err != nil {
+			return nil, err
+		}
+		if len(sig.Data) != len(data) {
+			return nil, newErrorf("invalid signature length: %s", s)
+		}
+		return sig, nil
+	}
+
+	if len(data) != 65 {
+		return nil, newErrorf("invalid signature length: %s", s)
+	}
+	return &Signature{Type: keyType, Data: data}, nil
+}
+
+func (sig *Signature) String() string {
+	return encodeKeyString("SIG_", sig.Type, sig.Data)
+}
+
+func (sig *Signature) Pack() []byte {
+	enc := NewEncoder(sig.Size())
+	enc.PackUint8(uint8(sig.Type))
+	enc.WriteBytes(sig.Data)
+	return enc.GetBytes()
+}
+
+func (sig *Signature) Unpack(data []byte) (int, error) {
+	dec := NewDecoder(data)
+	keyType, err := dec.UnpackUint8()
+	if err != nil {
+		return 0, err
+	}
+
+	sig.Type = KeyType(keyType)
+	if err := sig.unpackData(dec); err != nil {
+		return 0, err
+	}
+	return dec.Pos(), nil
+}
+
+func (sig *Signature) unpackData(dec *Decoder) error {
+	start := dec.Pos()
+	switch sig.Type {
+	case KeyTypeK1, KeyTypeR1:
+		if err := dec.checkPos(65); err != nil {
+			return err
+		}
+		dec.incPos(65)
+	case KeyTypeWA:
+		if err := dec.checkPos(65); err != nil {
+			return err
+		}
+		dec.incPos(65)
+		if _, err := dec.UnpackBytes(); err != nil {
+			return err
+		}
+		if _, err := dec.UnpackString(); err != nil {
+			return err
+		}
+	default:
+		return newErrorf("unsupported signature type %d", sig.Type)
+	}
+	sig.Data = make([]byte, dec.Pos()-start)
+	copy(sig.Data, dec.buf[start:dec.Pos()])
+	return nil
+}
+
+func (sig *Signature) Size() int {
+	return 1 + len(sig.Data)
+}
+
+// RecoverPublicKey recovers the public key that signed digest
+func RecoverPublicKey(digest []byte, sig *Signature) (*PublicKey, error) {
+	if len(digest) != 32 {
+		return nil, newErrorf("invalid digest length")
+	}
+
+	switch sig.Type {
+	case KeyTypeK1:
+		pub, err := secp256k1.Recover(digest, secp256k1.NewSignature(sig.Data))
+		if err != nil {
+			return nil, newError(err)
+		}
+		return &PublicKey{Type: KeyTypeK1, Data: pub.Data[:]}, nil
+	case KeyTypeR1:
+		pub, err := recoverR1(digest, sig.Data)
+		if err != nil {
+			return nil, err
+		}
+		return &PublicKey{Type: KeyTypeR1, Data: pub}, nil
+	case KeyTypeWA:
+		return sig.recoverWA(digest)
+	default:
+		return nil, newErrorf("unsupported signature type %d", sig.Type)
+	}
+}
+
+// recoverWA recovers a WebAuthn public key, the authenticator signs
+// sha256(auth_data || sha256(client_json)) and client_json carries digest as the challenge
+func (sig *Signature) recoverWA(digest []byte) (*PublicKey, error) {
+	if len(sig.Data) < 65 {
+		return nil, newErrorf("invalid signature length")
+	}
+
+	dec := NewDecoder(sig.Data[65:])
+	authData, err := dec.UnpackBytes()
+	if err != nil {
+		return nil, err
+	}
+	clientJson, err := dec.UnpackString()
+	if err != nil {
+		return nil, err
+	}
+
+	client := struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+		Origin    string `json:"origin"`
+	}{}
+	if err := json.Unmarshal([]byte(clientJson), &client); err != nil {
+		return nil, newError(err)
+	}
+	if client.Type != "webauthn.get" {
+		return nil, newErrorf("invalid webauthn type %s", client.Type)
+	}
+	challenge, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(client.Challenge, "="))
+	if err != nil || !bytes.Equal(challenge, digest) {
+		return nil, newErrorf("webauthn challenge does not match the digest")
+	}
+
+	//the rpid is the host of the origin, its hash starts the authenticator data
+	if !strings.HasPrefix(client.Origin, "https://") {
+		return nil, newErrorf("webauthn origin must be https")
+	}
+	rpid := strings.TrimPrefix(client.Origin, "https://")
+	if i := strings.IndexByte(rpid, ':'); i >= 0 {
+		rpid = rpid[:i]
+	}
+	if len(authData) < 37 {
+		return nil, newErrorf("invalid webauthn auth data length")
+	}
+	rpidHash := sha256.Sum256([]byte(rpid))
+	if !bytes.Equal(rpidHash[:], authData[:32]) {
+		return nil, newErrorf("webauthn rpid does not match the auth data")
+	}
+
+	var userPresence uint8
+	if authData[32]&0x04 != 0 {
+		userPresence = 2
+	} else if authData[32]&0x01 != 0 {
+		userPresence = 1
+	}
+
+	clientHash := sha256.Sum256([]byte(clientJson))
+	signed := sha256.Sum256(append(append([]byte{}, authData...), clientHash[:]...))
+	pub, err := recoverR1(signed[:], sig.Data[:65])
+	if err != nil {
+		return nil, err
+	}
+
+	enc := NewEncoder(64)
+	enc.WriteBytes(pub)
+	enc.PackUint8(userPresence)
+	enc.PackString(rpid)
+	return &PublicKey{Type: KeyTypeWA, Data: enc.GetBytes()}, nil
+}
+
+// recoverR1 recovers a compressed secp256r1 public key from a compact signature, see SEC 1 v2 section 4.1.6
+func recoverR1(digest []byte, compact []byte) ([]byte, error) {
+	if len(compact) != 65 {
+		return nil, newErrorf("invalid signature length")
+	}
+
+	recId := int(compact[0]) - 27
+	if recId < 0 || recId >= 8 {
+		return nil, newErrorf("invalid recovery id")
+	}
+	recId &= 3
+
+	curve := elliptic.P256()
+	params := curve.Params()
+	r := new(big.Int).SetBytes(compact[1:33])
+	s := new(big.Int).SetBytes(compact[33:65])
+	if r.Sign() == 0 || s.Sign() == 0 || r.Cmp(params.N) >= 0 || s.Cmp(params.N) >= 0 {
+		return nil, newErrorf("invalid signature")
+	}
+
+	x := new(big.Int).Set(r)
+	if recId&2 != 0 {
+		x.Add(x, params.N)
+		if x.Cmp(params.P) >= 0 {
+			return nil, newErrorf("invalid signature")
+		}
+	}
+
+	compressed := make([]byte, 33)
+	compressed[0] = byte(2 + recId&1)
+	x.FillBytes(compressed[1:])
+	rx, ry := elliptic.UnmarshalCompressed(curve, compressed)
+	if rx == nil {
+		return nil, newErrorf("invalid signature")
+	}
+
+	//Q = r^-1 * (s*R - e*G)
+	rInv := new(big.Int).ModInverse(r, params.N)
+	e := new(big.Int).SetBytes(digest)
+	u1 := new(big.Int).Mul(e, rInv)
+	u1.Neg(u1)
+	u1.Mod(u1, params.N)
+	u2 := new(big.Int).Mul(s, rInv)
+	u2.Mod(u2, params.N)
+
+	x1, y1 := curve.ScalarBaseMult(u1.Bytes())
+	x2, y2 := curve.ScalarMult(rx, ry, u2.Bytes())
+	qx, qy := curve.Add(x1, y1, x2, y2)
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return nil, newErrorf("invalid signature")
+	}
+
+	pub := &ecdsa.PublicKey{Curve: curve, X: qx, Y: qy}
+	if !ecdsa.Verify(pub, digest, r, s) {
+		return nil, newErrorf("invalid signature")
+	}
+	return elliptic.MarshalCompressed(curve, qx, qy), nil
+}
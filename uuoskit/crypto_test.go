@@ -0,0 +1,207 @@
+package uuoskit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	secp256k1 "github.com/uuosio/go-secp256k1"
+)
+
+func TestK1Key(t *testing.T) {
+	secp256k1.Init()
+	assert := assert.New(t)
+
+	priv, err := NewPrivateKeyFromString("5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP79zkvFD3")
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(KeyTypeK1, priv.Type)
+	assert.Equal("5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP79zkvFD3", priv.String())
+
+	pub := priv.GetPublicKey()
+	assert.Equal("EOS6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5GDW5CV", pub.StringEOS())
+	assert.Equal("PUB_K1_6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5BoDq63", pub.String())
+
+	pub2, err := NewPublicKeyFromString(pub.String())
+	assert.Nil(err)
+	assert.Equal(pub, pub2)
+
+	digest := sha256.Sum256([]byte("hello"))
+	sig, err := priv.Sign(digest[:])
+	if err != nil {
+		panic(err)
+	}
+
+	sig2, err := NewSignatureFromString(sig.String())
+	assert.Nil(err)
+	assert.Equal(sig, sig2)
+
+	recovered, err := RecoverPublicKey(digest[:], sig)
+	assert.Nil(err)
+	assert.Equal(pub, recovered)
+}
+
+func TestR1Key(t *testing.T) {
+	assert := assert.New(t)
+
+	priv, err := GeneratePrivateKey(KeyTypeR1)
+	if err != nil {
+		panic(err)
+	}
+
+	strPriv := priv.String()
+	assert.Contains(strPriv, "PVT_R1_")
+	priv2, err := NewPrivateKeyFromString(strPriv)
+	assert.Nil(err)
+	assert.Equal(priv, priv2)
+
+	pub := priv.GetPublicKey()
+	assert.Contains(pub.String(), "PUB_R1_")
+	assert.Equal(pub.String(), pub.StringEOS())
+	pub2, err := NewPublicKeyFromString(pub.String())
+	assert.Nil(err)
+	assert.Equal(pub, pub2)
+
+	for i := 0; i < 10; i++ {
+		digest := sha256.Sum256([]byte{byte(i)})
+		sig, err := priv.Sign(digest[:])
+		if err != nil {
+			panic(err)
+		}
+		assert.Contains(sig.String(), "SIG_R1_")
+
+		sig2, err := NewSignatureFromString(sig.String())
+		assert.Nil(err)
+		assert.Equal(sig, sig2)
+
+		recovered, err := RecoverPublicKey(digest[:], sig)
+		assert.Nil(err)
+		assert.Equal(pub, recovered)
+	}
+
+	//checksum is computed with the R1 suffix
+	strPub := pub.String()
+	_, err = NewPublicKeyFromString("PUB_K1_" + strPub[len("PUB_R1_"):])
+	assert.NotNil(err)
+}
+
+func TestR1AbiSerializer(t *testing.T) {
+	assert := assert.New(t)
+
+	priv, err := GeneratePrivateKey(KeyTypeR1)
+	if err != nil {
+		panic(err)
+	}
+	pub := priv.GetPublicKey()
+	digest := sha256.Sum256([]byte("hello"))
+	sig, err := priv.Sign(digest[:])
+	if err != nil {
+		panic(err)
+	}
+
+	abi := &ABI{}
+	enc := NewEncoder(34)
+	err = abi.ParseAbiStringValue(enc, "public_key", strconv.Quote(pub.String()))
+	if err != nil {
+		panic(err)
+	}
+	packed := enc.GetBytes()
+	assert.Equal("01"+hex.EncodeToString(pub.Data), hex.EncodeToString(packed))
+
+	dec := NewDecoder(packed)
+	v, err := abi.unpackAbiStructField(dec, "public_key")
+	assert.Nil(err)
+	assert.Equal(pub.String(), v)
+
+	enc = NewEncoder(66)
+	err = abi.ParseAbiStringValue(enc, "signature", strconv.Quote(sig.String()))
+	if err != nil {
+		panic(err)
+	}
+	packed = enc.GetBytes()
+	assert.Equal(66, len(packed))
+	assert.Equal(byte(1), packed[0])
+
+	dec = NewDecoder(packed)
+	v, err = abi.unpackAbiStructField(dec, "signature")
+	assert.Nil(err)
+	assert.Equal(sig.String(), v)
+}
+
+func TestWebAuthnAbiSerializer(t *testing.T) {
+	assert := assert.New(t)
+
+	priv, err := GeneratePrivateKey(KeyTypeR1)
+	if err != nil {
+		panic(err)
+	}
+
+	enc := NewEncoder(64)
+	enc.WriteBytes(priv.GetPublicKey().Data)
+	enc.PackUint8(1)
+	enc.PackString("example.com")
+	pub := &PublicKey{Type: KeyTypeWA, Data: enc.GetBytes()}
+	assert.Equal(uint8(1), pub.UserPresence())
+	assert.Equal("example.com", pub.RPID())
+
+	strPub := pub.String()
+	assert.Contains(strPub, "PUB_WA_")
+	assert.Equal(strPub, pub.StringEOS())
+	pub2, err := NewPublicKeyFromString(strPub)
+	assert.Nil(err)
+	assert.Equal(pub, pub2)
+
+	abi := &ABI{}
+	enc = NewEncoder(64)
+	err = abi.ParseAbiStringValue(enc, "public_key", strconv.Quote(strPub))
+	if err != nil {
+		panic(err)
+	}
+	packed := enc.GetBytes()
+	assert.Equal("02"+hex.EncodeToString(pub.Data), hex.EncodeToString(packed))
+
+	v, err := abi.unpackAbiStructField(NewDecoder(packed), "public_key")
+	assert.Nil(err)
+	assert.Equal(strPub, v)
+
+	digest := sha256.Sum256([]byte("hello"))
+	sig, err := priv.Sign(digest[:])
+	if err != nil {
+		panic(err)
+	}
+	enc = NewEncoder(256)
+	enc.WriteBytes(sig.Data)
+	enc.PackBytes(make([]byte, 37))
+	enc.PackString(`{"type":"webauthn.get","challenge":"","origin":"https://example.com"}`)
+	waSig := &Signature{Type: KeyTypeWA, Data: enc.GetBytes()}
+
+	strSig := waSig.String()
+	assert.Contains(strSig, "SIG_WA_")
+	sig2, err := NewSignatureFromString(strSig)
+	assert.Nil(err)
+	assert.Equal(waSig, sig2)
+
+	enc = NewEncoder(256)
+	err = abi.ParseAbiStringValue(enc, "signature", strconv.Quote(strSig))
+	if err != nil {
+		panic(err)
+	}
+	packed = append(enc.GetBytes(), 0xff)
+	assert.Equal(byte(2), packed[0])
+
+	dec := NewDecoder(packed)
+	v, err = abi.unpackAbiStructField(dec, "signature")
+	assert.Nil(err)
+	assert.Equal(strSig, v)
+	assert.Equal(len(packed)-1, dec.Pos())
+
+	//truncated WebAuthn signature
+	_, err = abi.unpackAbiStructField(NewDecoder(packed[:70]), "signature")
+	assert.NotNil(err)
+
+	_, err = NewPrivateKeyFromString(encodeKeyString("PVT_", KeyTypeWA, priv.Data[:]))
+	assert.NotNil(err)
+}
@@ -0,0 +1,345 @@
+package uuoskit
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// ids of the transaction extensions known by nodeos
+const (
+	DeferredTransactionGenerationContextId uint16 = 0
+	ResourcePayerId                        uint16 = 1
+)
+
+// DeferredTransactionGenerationContext is attached by nodeos to deferred transactions sent by contracts
+type DeferredTransactionGenerationContext struct {
+	SenderTrxId Bytes   `json:"sender_trx_id"`
+	SenderId    Uint128 `json:"sender_id"`
+	Sender      Name    `json:"sender"`
+}
+
+func (t *DeferredTransactionGenerationContext) Pack() []byte {
+	enc := NewEncoder(t.Size())
+	senderTrxId := [32]byte{}
+	copy(senderTrxId[:], t.SenderTrxId)
+	enc.WriteBytes(senderTrxId[:])
+	enc.WriteBytes(t.SenderId[:])
+	enc.Pack(&t.Sender)
+	return enc.GetBytes()
+}
+
+func (t *DeferredTransactionGenerationContext) Unpack(data []byte) (int, error) {
+	dec := NewDecoder(data)
+	t.SenderTrxId = make([]byte, 32)
+	if err := dec.Read(t.SenderTrxId); err != nil {
+		return 0, err
+	}
+	if err := dec.Read(t.SenderId[:]); err != nil {
+		return 0, err
+	}
+	if _, err := dec.Unpack(&t.Sender); err != nil {
+		return 0, err
+	}
+	return dec.Pos(), nil
+}
+
+func (t *DeferredTransactionGenerationContext) Size() int {
+	return 32 + 16 + 8
+}
+
+// ResourcePayer lets payer pay the NET, CPU and RAM consumed by a transaction,
+// payer has to authorize the transaction as well
+type ResourcePayer struct {
+	Payer          Name   `json:"payer"`
+	MaxNetBytes    uint64 `json:"max_net_bytes"`
+	MaxCpuUs       uint64 `json:"max_cpu_us"`
+	MaxMemoryBytes uint64 `json:"max_memory_bytes"`
+}
+
+func (t *ResourcePayer) Pack() []byte {
+	enc := NewEncoder(t.Size())
+	enc.Pack(&t.Payer)
+	enc.PackUint64(t.MaxNetBytes)
+	enc.PackUint64(t.MaxCpuUs)
+	enc.PackUint64(t.MaxMemoryBytes)
+	return enc.GetBytes()
+}
+
+func (t *ResourcePayer) Unpack(data []byte) (int, error) {
+	var err error
+	dec := NewDecoder(data)
+	if _, err = dec.Unpack(&t.Payer); err != nil {
+		return 0, err
+	}
+
+	t.MaxNetBytes, err = dec.UnpackUint64()
+	if err != nil {
+		return 0, err
+	}
+
+	t.MaxCpuUs, err = dec.UnpackUint64()
+	if err != nil {
+		return 0, err
+	}
+
+	t.MaxMemoryBytes, err = dec.UnpackUint64()
+	if err != nil {
+		return 0, err
+	}
+	return dec.Pos(), nil
+}
+
+func (t *ResourcePayer) Size() int {
+	return 8 * 4
+}
+
+func NewResourcePayerExtension(payer Name, maxNetBytes uint64, maxCpuUs uint64, maxMemoryBytes uint64) *TransactionExtension {
+	ext := &ResourcePayer{
+		Payer:          payer,
+		MaxNetBytes:    maxNetBytes,
+		MaxCpuUs:       maxCpuUs,
+		MaxMemoryBytes: maxMemoryBytes,
+	}
+	return &TransactionExtension{Type: ResourcePayerId, Data: ext.Pack()}
+}
+
+func NewDeferredTransactionGenerationContextExtension(senderTrxId string, senderId Uint128, sender Name) (*TransactionExtension, error) {
+	id, err := DecodeHash256(senderTrxId)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := &DeferredTransactionGenerationContext{
+		SenderTrxId: id,
+		SenderId:    senderId,
+		Sender:      sender,
+	}
+	return &TransactionExtension{Type: DeferredTransactionGenerationContextId, Data: ext.Pack()}, nil
+}
+
+// GetName returns the name of a known extension, or an empty string for unknown extensions
+func (t *TransactionExtension) GetName() string {
+	switch t.Type {
+	case DeferredTransactionGenerationContextId:
+		return "deferred_transaction_generation_context"
+	case ResourcePayerId:
+		return "resource_payer"
+	default:
+		return ""
+	}
+}
+
+// Decode unpacks a known extension to *DeferredTransactionGenerationContext or *ResourcePayer
+func (t *TransactionExtension) Decode() (interface{}, error) {
+	var ext Unpacker
+	switch t.Type {
+	case DeferredTransactionGenerationContextId:
+		ext = &DeferredTransactionGenerationContext{}
+	case ResourcePayerId:
+		ext = &ResourcePayer{}
+	default:
+		return nil, newErrorf("unknown transaction extension %d", t.Type)
+	}
+
+	n, err := ext.Unpack(t.Data)
+	if err != nil {
+		return nil, newErrorf("invalid %s extension: %v", t.GetName(), err)
+	}
+
+	if n != len(t.Data) {
+		return nil, newErrorf("invalid %s extension: %d bytes left", t.GetName(), len(t.Data)-n)
+	}
+	return ext, nil
+}
+
+func (t *TransactionExtension) GetResourcePayer() (*ResourcePayer, error) {
+	if t.Type != ResourcePayerId {
+		return nil, newErrorf("not a resource_payer extension")
+	}
+
+	ext, err := t.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return ext.(*ResourcePayer), nil
+}
+
+func (t *TransactionExtension) GetDeferredTransactionGenerationContext() (*DeferredTransactionGenerationContext, error) {
+	if t.Type != DeferredTransactionGenerationContextId {
+		return nil, newErrorf("not a deferred_transaction_generation_context extension")
+	}
+
+	ext, err := t.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return ext.(*DeferredTransactionGenerationContext), nil
+}
+
+// transactionExtensionJSON renders known extensions as structured json
+type transactionExtensionJSON struct {
+	Type uint16      `json:"type"`
+	Name string      `json:"name,omitempty"`
+	Data interface{} `json:"data"`
+}
+
+func (t *TransactionExtension) toJSON() *transactionExtensionJSON {
+	r := &transactionExtensionJSON{Type: t.Type, Name: t.GetName(), Data: Bytes(t.Data)}
+	if ext, err := t.Decode(); err == nil {
+		r.Data = ext
+	}
+	return r
+}
+
+// MarshalJSON renders the extension in the [1, "hex"] pair format of nodeos
+func (t TransactionExtension) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{t.Type, Bytes(t.Data)})
+}
+
+// UnmarshalJSON accepts {"type": 1, "data": "hex"}, a structured data object of a known extension,
+// and the [1, "hex"] pair format of nodeos
+func (t *TransactionExtension) UnmarshalJSON(b []byte) error {
+	var data json.RawMessage
+	if len(b) > 0 && b[0] == '[' {
+		pair := []json.RawMessage{}
+		if err := json.Unmarshal(b, &pair); err != nil {
+			return newError(err)
+		}
+
+		if len(pair) != 2 {
+			return newErrorf("invalid transaction extension: %s", string(b))
+		}
+
+		if err := json.Unmarshal(pair[0], &t.Type); err != nil {
+			return newError(err)
+		}
+		data = pair[1]
+	} else {
+		ext := struct {
+			Type uint16          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}{}
+		if err := json.Unmarshal(b, &ext); err != nil {
+			return newError(err)
+		}
+		t.Type = ext.Type
+		data = ext.Data
+	}
+
+	if len(data) == 0 || data[0] != '{' {
+		var bs Bytes
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &bs); err != nil {
+				//base64 encoded by older versions
+				var raw []byte
+				if json.Unmarshal(data, &raw) != nil {
+					return err
+				}
+				bs = raw
+			}
+		}
+		t.Data = bs
+		return nil
+	}
+
+	var ext Packer
+	switch t.Type {
+	case DeferredTransactionGenerationContextId:
+		ext = &DeferredTransactionGenerationContext{}
+	case ResourcePayerId:
+		ext = &ResourcePayer{}
+	default:
+		return newErrorf("unknown transaction extension %d", t.Type)
+	}
+
+	if err := json.Unmarshal(data, ext); err != nil {
+		return newError(err)
+	}
+	t.Data = ext.Pack()
+	return nil
+}
+
+// ValidateExtensions checks that extension ids are in ascending order without duplicates
+// and that known extensions can be decoded, as nodeos does
+func (t *Transaction) ValidateExtensions() error {
+	for i := range t.Extention {
+		ext := &t.Extention[i]
+		if i > 0 && ext.Type <= t.Extention[i-1].Type {
+			return newErrorf("transaction extensions are not in ascending order or contain duplicated id %d", ext.Type)
+		}
+
+		if ext.GetName() == "" {
+			continue
+		}
+		if _, err := ext.Decode(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddExtension inserts ext in the order of extension ids, an existing extension with the same id is replaced
+func (t *Transaction) AddExtension(ext *TransactionExtension) error {
+	if ext.GetName() != "" {
+		if _, err := ext.Decode(); err != nil {
+			return err
+		}
+	}
+
+	i := sort.Search(len(t.Extention), func(i int) bool {
+		return t.Extention[i].Type >= ext.Type
+	})
+
+	if i < len(t.Extention) && t.Extention[i].Type == ext.Type {
+		t.Extention[i] = *ext
+		return nil
+	}
+
+	t.Extention = append(t.Extention, TransactionExtension{})
+	copy(t.Extention[i+1:], t.Extention[i:])
+	t.Extention[i] = *ext
+	return nil
+}
+
+func (t *Transaction) getExtension(id uint16) *TransactionExtension {
+	for i := range t.Extention {
+		if t.Extention[i].Type == id {
+			return &t.Extention[i]
+		}
+	}
+	return nil
+}
+
+// SetResourcePayer makes payer pay for the resources used by the transaction
+func (t *Transaction) SetResourcePayer(payer Name, maxNetBytes uint64, maxCpuUs uint64, maxMemoryBytes uint64) error {
+	return t.AddExtension(NewResourcePayerExtension(payer, maxNetBytes, maxCpuUs, maxMemoryBytes))
+}
+
+// GetResourcePayer returns nil if the transaction does not have a resource_payer extension
+func (t *Transaction) GetResourcePayer() (*ResourcePayer, error) {
+	ext := t.getExtension(ResourcePayerId)
+	if ext == nil {
+		return nil, nil
+	}
+	return ext.GetResourcePayer()
+}
+
+// GetDeferredTransactionGenerationContext returns nil if the transaction is not a deferred transaction
+func (t *Transaction) GetDeferredTransactionGenerationContext() (*DeferredTransactionGenerationContext, error) {
+	ext := t.getExtension(DeferredTransactionGenerationContextId)
+	if ext == nil {
+		return nil, nil
+	}
+	return ext.GetDeferredTransactionGenerationContext()
+}
+
+func (t *PackedTransaction) AddExtension(ext *TransactionExtension) error {
+	if t.PackedTx != nil {
+		return newErrorf("can not add extension after pack or sign")
+	}
+	return t.tx.AddExtension(ext)
+}
+
+func (t *PackedTransaction) SetResourcePayer(payer Name, maxNetBytes uint64, maxCpuUs uint64, maxMemoryBytes uint64) error {
+	return t.AddExtension(NewResourcePayerExtension(payer, maxNetBytes, maxCpuUs, maxMemoryBytes))
+}
@@ -3,6 +3,8 @@ package uuoskit
 import (
 	"encoding/json"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestJsonValue(t *testing.T) {
@@ -44,3 +46,57 @@ func TestJsonValue(t *testing.T) {
 		t.Logf(string(r))
 	}
 }
+
+func TestJsonValueTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	data := `{"a":null,"b":true,"c":1.5,"d":"x\"y","e":[1,"2"],"f":{}}`
+	v := JsonValue{}
+	err := json.Unmarshal([]byte(data), &v)
+	if err != nil {
+		panic(err)
+	}
+
+	r, err := v.GetTyped("a")
+	assert.Nil(err)
+	assert.Nil(r)
+	r, _ = v.GetTyped("b")
+	assert.Equal(true, r)
+	r, _ = v.GetTyped("c")
+	assert.Equal(json.Number("1.5"), r)
+	r, _ = v.GetTyped("d")
+	assert.Equal(`x"y`, r)
+
+	//Get returns the text of scalars
+	for key, text := range map[string]string{"a": "null", "b": "true", "c": "1.5", "d": `x"y`} {
+		r, err = v.Get(key)
+		assert.Nil(err)
+		assert.Equal(text, r)
+	}
+	r, _ = v.Get("f")
+	assert.Equal(map[string]JsonValue{}, r)
+
+	r, _ = v.Get("e", 1)
+	e1 := r.(JsonValue)
+	s, ok := e1.GetStringValue()
+	assert.True(ok)
+	assert.Equal("2", s)
+
+	s, err = v.GetString("c")
+	assert.Nil(err)
+	assert.Equal("1.5", s)
+	s, err = v.GetString("e", 0)
+	assert.Nil(err)
+	assert.Equal("1", s)
+	_, err = v.GetString("a")
+	assert.NotNil(err)
+
+	bs, err := json.Marshal(v)
+	assert.Nil(err)
+	assert.Equal(data, string(bs))
+
+	null := NewJsonValue(nil)
+	assert.True(null.IsNull())
+	bs, _ = json.Marshal(null)
+	assert.Equal("null", string(bs))
+}
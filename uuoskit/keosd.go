@@ -0,0 +1,158 @@
+package uuoskit
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// KeosdWallet is a client of the /v1/wallet/* api of keosd,
+// it implements Signer so that private keys never have to be imported into this process.
+type KeosdWallet struct {
+	rpc *Rpc
+}
+
+type keosdErrorDetail struct {
+	Message string `json:"message"`
+}
+
+type keosdError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Error   *struct {
+		Code    int                `json:"code"`
+		Name    string             `json:"name"`
+		What    string             `json:"what"`
+		Details []keosdErrorDetail `json:"details"`
+	} `json:"error"`
+}
+
+// NewKeosdWallet creates a keosd client, url is either a http url like http://127.0.0.1:8900
+// or the unix socket keosd listens on by default, e.g. unix:///root/eosio-wallet/keosd.sock
+func NewKeosdWallet(url string) *KeosdWallet {
+	if !strings.HasPrefix(url, "unix://") {
+		return &KeosdWallet{rpc: NewRpc(url)}
+	}
+
+	socket := strings.TrimPrefix(url, "unix://")
+	tr := &http.Transport{
+		MaxIdleConns:       10,
+		IdleConnTimeout:    30 * time.Second,
+		DisableCompression: true,
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}
+
+	rpc := &Rpc{}
+	rpc.url = "http://localhost"
+	rpc.client = &http.Client{Transport: tr}
+	return &KeosdWallet{rpc: rpc}
+}
+
+func (k *KeosdWallet) call(endpoint string, params interface{}, result interface{}) error {
+	r, err := k.rpc.Call("wallet", endpoint, params)
+	if err != nil {
+		return newError(err)
+	}
+
+	if len(r) > 0 && r[0] == '{' {
+		e := keosdError{}
+		if err := json.Unmarshal(r, &e); err == nil && e.Error != nil {
+			msg := e.Error.What
+			if len(e.Error.Details) > 0 {
+				msg += ": " + e.Error.Details[0].Message
+			}
+			return newErrorf("keosd %s error: %s", endpoint, msg)
+		}
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(r, result); err != nil {
+		return newError(err)
+	}
+	return nil
+}
+
+// Unlock unlocks the keosd wallet with name
+func (k *KeosdWallet) Unlock(name string, password string) error {
+	return k.call("unlock", []string{name, password}, nil)
+}
+
+// ListKeys returns the public keys of the keosd wallet with name mapped to their private keys
+func (k *KeosdWallet) ListKeys(name string, password string) (map[string]string, error) {
+	pairs := [][]string{}
+	if err := k.call("list_keys", []string{name, password}, &pairs); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		if len(pair) != 2 {
+			return nil, newErrorf("invalid key pair returned by keosd")
+		}
+		keys[pair[0]] = pair[1]
+	}
+	return keys, nil
+}
+
+// GetPublicKeys returns the public keys of all unlocked keosd wallets
+func (k *KeosdWallet) GetPublicKeys() ([]string, error) {
+	keys := []string{}
+	if err := k.call("get_public_keys", "", &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// SignTransaction asks keosd to sign tx with the private keys of publicKeys and returns the signatures,
+// contextFreeData is included in the digest keosd signs
+func (k *KeosdWallet) SignTransaction(tx *Transaction, contextFreeData []Bytes, publicKeys []string, chainId string) ([]string, error) {
+	if _, err := DecodeHash256(chainId); err != nil {
+		return nil, err
+	}
+
+	if contextFreeData == nil {
+		contextFreeData = []Bytes{}
+	}
+	signedTx := SignedTransaction{
+		Transaction:     tx,
+		Signatures:      []string{},
+		ContextFreeData: contextFreeData,
+	}
+	args := []interface{}{signedTx, publicKeys, chainId}
+
+	result := struct {
+		Signatures []string `json:"signatures"`
+	}{}
+	if err := k.call("sign_transaction", args, &result); err != nil {
+		return nil, err
+	}
+	return result.Signatures, nil
+}
+
+// SignDigest implements Signer
+func (k *KeosdWallet) SignDigest(digest []byte, pubKey string) (string, error) {
+	if len(digest) != 32 {
+		return "", newErrorf("digest must be 32 bytes")
+	}
+
+	sig := ""
+	if err := k.call("sign_digest", []string{hex.EncodeToString(digest), pubKey}, &sig); err != nil {
+		return "", err
+	}
+	return sig, nil
+}
+
+// GetAvailableKeys implements Signer
+func (k *KeosdWallet) GetAvailableKeys() ([]string, error) {
+	return k.GetPublicKeys()
+}
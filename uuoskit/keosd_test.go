@@ -0,0 +1,178 @@
+package uuoskit
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	secp256k1 "github.com/uuosio/go-secp256k1"
+)
+
+func newTestKeosd(t *testing.T, signer *testSigner) map[string]func(body []byte) interface{} {
+	keosdError := map[string]interface{}{
+		"code":    500,
+		"message": "Internal Service Error",
+		"error": map[string]interface{}{
+			"code":    3120006,
+			"name":    "wallet_invalid_password_exception",
+			"what":    "Invalid wallet password",
+			"details": []interface{}{map[string]interface{}{"message": "Invalid password for wallet: \"default\""}},
+		},
+	}
+
+	return map[string]func(body []byte) interface{}{
+		"/v1/wallet/unlock": func(body []byte) interface{} {
+			args := []string{}
+			json.Unmarshal(body, &args)
+			if args[1] != "123456" {
+				return keosdError
+			}
+			return map[string]interface{}{}
+		},
+		"/v1/wallet/list_keys": func(body []byte) interface{} {
+			pairs := [][]string{}
+			for pub, priv := range signer.keys {
+				pairs = append(pairs, []string{pub, priv.String()})
+			}
+			return pairs
+		},
+		"/v1/wallet/get_public_keys": func(body []byte) interface{} {
+			keys, _ := signer.GetAvailableKeys()
+			return keys
+		},
+		"/v1/wallet/sign_digest": func(body []byte) interface{} {
+			args := []string{}
+			json.Unmarshal(body, &args)
+			digest, _ := hex.DecodeString(args[0])
+			sig, err := signer.SignDigest(digest, args[1])
+			if err != nil {
+				return keosdError
+			}
+			return sig
+		},
+		"/v1/wallet/sign_transaction": func(body []byte) interface{} {
+			args := []json.RawMessage{}
+			json.Unmarshal(body, &args)
+
+			tx := &Transaction{}
+			if err := json.Unmarshal(args[0], tx); err != nil {
+				t.Error(err)
+			}
+			signedTx := struct {
+				ContextFreeData []Bytes `json:"context_free_data"`
+			}{}
+			if err := json.Unmarshal(args[0], &signedTx); err != nil {
+				t.Error(err)
+			}
+			keys := []string{}
+			json.Unmarshal(args[1], &keys)
+			chainId := ""
+			json.Unmarshal(args[2], &chainId)
+
+			_chainId, _ := DecodeHash256(chainId)
+			digest := calcDigest(_chainId, tx.Pack(), signedTx.ContextFreeData)
+			signatures := []string{}
+			for _, key := range keys {
+				sig, _ := signer.SignDigest(digest, key)
+				signatures = append(signatures, sig)
+			}
+			return map[string]interface{}{"signatures": signatures, "context_free_data": []string{}}
+		},
+	}
+}
+
+func TestKeosdWallet(t *testing.T) {
+	secp256k1.Init()
+	assert := assert.New(t)
+
+	pub := "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"
+	priv := "5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL"
+	keosd := newTestNode(t, newTestKeosd(t, newTestSigner(priv)))
+	defer keosd.Close()
+
+	wallet := NewKeosdWallet(keosd.URL)
+	err := wallet.Unlock("default", "654321")
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "Invalid wallet password")
+	assert.Nil(wallet.Unlock("default", "123456"))
+
+	keys, err := wallet.ListKeys("default", "123456")
+	assert.Nil(err)
+	assert.Equal(map[string]string{pub: priv}, keys)
+
+	pubKeys, err := wallet.GetAvailableKeys()
+	assert.Nil(err)
+	assert.Equal([]string{pub}, pubKeys)
+
+	chainId := testChainInfo["chain_id"].(string)
+	tx := NewTransaction(1122)
+	tx.AddAction(NewAction(NewName("hello"), NewName("sayhello"), []PermissionLevel{{NewName("hello"), NewName("active")}}, "hello"))
+	expected, err := tx.Sign(priv, chainId)
+	if err != nil {
+		panic(err)
+	}
+
+	signatures, err := wallet.SignTransaction(tx, nil, []string{pub}, chainId)
+	assert.Nil(err)
+	assert.Equal([]string{expected}, signatures)
+
+	//context free data is part of the digest
+	cfdTx := NewPackedTransaction(tx)
+	cfdTx.SetChainId(chainId)
+	cfdTx.AddContextFreeData([]byte("hello"))
+	expectedCfd, err := cfdTx.SignByPrivateKey(priv)
+	if err != nil {
+		panic(err)
+	}
+	assert.NotEqual(expected, expectedCfd)
+	signatures, err = wallet.SignTransaction(tx, cfdTx.GetSignedTransaction().ContextFreeData, []string{pub}, chainId)
+	assert.Nil(err)
+	assert.Equal([]string{expectedCfd}, signatures)
+
+	packedTx := NewPackedTransaction(tx)
+	packedTx.SetChainId(chainId)
+	sig, err := packedTx.SignWithSigner(wallet, pub)
+	assert.Nil(err)
+	assert.Equal(expected, sig)
+
+	_, err = wallet.SignDigest(make([]byte, 32), "EOS6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5GDW5CV")
+	assert.NotNil(err)
+}
+
+func TestChainApiWithKeosd(t *testing.T) {
+	secp256k1.Init()
+	assert := assert.New(t)
+
+	pub := "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"
+	signer := newTestSigner("5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL")
+	keosd := newTestNode(t, newTestKeosd(t, signer))
+	defer keosd.Close()
+
+	var pushed PackedTransaction
+	node := newTestNode(t, map[string]func(body []byte) interface{}{
+		"/v1/chain/get_info": func(body []byte) interface{} {
+			return testChainInfo
+		},
+		"/v1/chain/get_required_keys": func(body []byte) interface{} {
+			return GetRequiredKeysResult{RequiredKeys: []string{pub}}
+		},
+		"/v1/chain/push_transaction": func(body []byte) interface{} {
+			json.Unmarshal(body, &pushed)
+			return map[string]interface{}{"transaction_id": "00"}
+		},
+	})
+	defer node.Close()
+
+	api := NewChainApi(node.URL)
+	api.SetSigner(NewKeosdWallet(keosd.URL))
+	//the endpoint selected from server_version_string can be overridden
+	assert.Nil(api.SetPushEndpoint(PushTransactionEndpoint))
+	action := NewAction(NewName("hello"), NewName("sayhello"), []PermissionLevel{{NewName("hello"), NewName("active")}}, "hello")
+	_, err := api.PushAction(action)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal([]string{pub}, signer.signed)
+	assert.Equal(1, len(pushed.Signatures))
+}
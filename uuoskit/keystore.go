@@ -0,0 +1,144 @@
+package uuoskit
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	keystoreVersion = 1
+
+	keystoreScryptN = 1 << 15
+	keystoreScryptR = 8
+	keystoreScryptP = 1
+	keystoreKeyLen  = 32
+	keystoreSaltLen = 32
+)
+
+// keystoreFile is the on-disk layout of an encrypted wallet.
+// CipherKeys holds the AES-256-GCM encrypted json array of private keys,
+// the encryption key is derived from the wallet password with scrypt.
+type keystoreFile struct {
+	Version    int   `json:"version"`
+	Salt       Bytes `json:"salt"`
+	Nonce      Bytes `json:"nonce"`
+	CipherKeys Bytes `json:"cipher_keys"`
+}
+
+func newKeystoreSalt() ([]byte, error) {
+	salt := make([]byte, keystoreSaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, newError(err)
+	}
+	return salt, nil
+}
+
+func deriveKeystoreKey(password string, salt []byte) ([]byte, error) {
+	key, err := scrypt.Key([]byte(password), salt, keystoreScryptN, keystoreScryptR, keystoreScryptP, keystoreKeyLen)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return key, nil
+}
+
+func readKeystoreFile(path string) (*keystoreFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, newError(err)
+	}
+
+	ks := &keystoreFile{}
+	if err := json.Unmarshal(data, ks); err != nil {
+		return nil, newError(err)
+	}
+
+	if ks.Version != keystoreVersion {
+		return nil, newErrorf("unsupported wallet file version %d", ks.Version)
+	}
+
+	if len(ks.Salt) != keystoreSaltLen {
+		return nil, newErrorf("invalid wallet file: bad salt")
+	}
+	return ks, nil
+}
+
+func writeKeystoreFile(path string, ks *keystoreFile) error {
+	data, err := json.Marshal(ks)
+	if err != nil {
+		return newError(err)
+	}
+
+	//write to a temporary file first so that a crash never leaves a truncated wallet behind
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return newError(err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return newError(err)
+	}
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return newError(err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return newError(err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return newError(err)
+	}
+	return nil
+}
+
+func encryptKeystore(key []byte, plain []byte) (nonce []byte, cipherText []byte, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, newError(err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, newError(err)
+	}
+
+	nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, newError(err)
+	}
+	return nonce, gcm.Seal(nil, nonce, plain, nil), nil
+}
+
+func decryptKeystore(key []byte, nonce []byte, cipherText []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, newError(err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, newError(err)
+	}
+
+	if len(nonce) != gcm.NonceSize() {
+		return nil, newErrorf("invalid wallet file: bad nonce")
+	}
+
+	plain, err := gcm.Open(nil, nonce, cipherText, nil)
+	if err != nil {
+		return nil, newErrorf("invalid password")
+	}
+	return plain, nil
+}
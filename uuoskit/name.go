@@ -72,6 +72,25 @@ func N2S(value uint64) string {
 	return string(str[:i+1])
 }
 
+// IsValidName checks that s is a normalized name as nodeos requires: at most 13 characters of
+// .12345a-z, the 13th one in .12345a-j, and no trailing dots
+func IsValidName(s string) bool {
+	if len(s) > 13 {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '.' && char_to_symbol(c) == 0 {
+			return false
+		}
+		if i == 12 && c > 'j' {
+			return false
+		}
+	}
+	return N2S(S2N(s)) == s
+}
+
 type Name struct {
 	N uint64
 }
@@ -0,0 +1,384 @@
+package uuoskit
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Structs without Pack and Unpack methods are packed field by field in declaration order by Encoder.Pack and
+// Decoder.Unpack. Pointer fields are optionals, slices are arrays with a length prefix, Go arrays are fixed size arrays
+// without one, and Packer and Unpacker fields use their own methods. The eosio struct tag changes how a field is packed:
+//
+//	Amount   uint32  `eosio:"varuint32"`        //also int or uint, for slices and arrays it applies to the elements
+//	Delta    int32   `eosio:"varint32"`
+//	Memo     *string `eosio:"optional"`         //the same as without the tag
+//	Referrer *Name   `eosio:"binary_extension"` //left out if nil, binary extensions have to be the last fields
+//	cache    int     `eosio:"-"`                //not packed, as unexported fields
+type eosioTag struct {
+	varint    bool
+	varuint   bool
+	optional  bool
+	extension bool
+}
+
+type reflectField struct {
+	index int
+	name  string
+	tag   eosioTag
+}
+
+var (
+	packerType   = reflect.TypeOf((*Packer)(nil)).Elem()
+	unpackerType = reflect.TypeOf((*Unpacker)(nil)).Elem()
+	//reflect.Type to []reflectField
+	reflectFieldsCache sync.Map
+)
+
+func parseEosioTag(tag string) (eosioTag, error) {
+	t := eosioTag{}
+	if tag == "" {
+		return t, nil
+	}
+	for _, option := range strings.Split(tag, ",") {
+		switch option {
+		case "varint32":
+			t.varint = true
+		case "varuint32":
+			t.varuint = true
+		case "optional":
+			t.optional = true
+		case "binary_extension":
+			t.extension = true
+		default:
+			return t, newErrorf("unknown eosio tag option %s", option)
+		}
+	}
+	if t.varint && t.varuint {
+		return t, newErrorf("invalid eosio tag %s", tag)
+	}
+	return t, nil
+}
+
+func getReflectFields(typ reflect.Type) ([]reflectField, error) {
+	if fields, ok := reflectFieldsCache.Load(typ); ok {
+		return fields.([]reflectField), nil
+	}
+
+	fields := make([]reflectField, 0, typ.NumField())
+	extension := false
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tagValue := field.Tag.Get("eosio")
+		if field.PkgPath != "" || tagValue == "-" {
+			continue
+		}
+
+		tag, err := parseEosioTag(tagValue)
+		if err != nil {
+			return nil, newErrorf("%s.%s: %v", typ.Name(), field.Name, err)
+		}
+		if tag.optional && field.Type.Kind() != reflect.Ptr {
+			return nil, newErrorf("%s.%s: optional field is not a pointer", typ.Name(), field.Name)
+		}
+		if tag.extension {
+			extension = true
+		} else if extension {
+			return nil, newErrorf("%s.%s: follows a binary extension field", typ.Name(), field.Name)
+		}
+		fields = append(fields, reflectField{i, field.Name, tag})
+	}
+	reflectFieldsCache.Store(typ, fields)
+	return fields, nil
+}
+
+// elemTag returns the tag options that apply to the elements of slices and arrays
+func (t eosioTag) elemTag() eosioTag {
+	return eosioTag{varint: t.varint, varuint: t.varuint}
+}
+
+func (enc *Encoder) packReflectValue(rv reflect.Value, tag eosioTag) error {
+	if !rv.IsValid() {
+		return newErrorf("can not pack nil")
+	}
+
+	if rv.Type().Implements(packerType) {
+		if rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return newErrorf("can not pack nil %s", rv.Type())
+		}
+		enc.Write(rv.Interface().(Packer).Pack())
+		return nil
+	}
+	if reflect.PtrTo(rv.Type()).Implements(packerType) {
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		enc.Write(p.Interface().(Packer).Pack())
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return newErrorf("can not pack nil %s", rv.Type())
+		}
+		return enc.packReflectValue(rv.Elem(), tag)
+	case reflect.Bool:
+		enc.PackBool(rv.Bool())
+	case reflect.Int8:
+		enc.PackInt8(int8(rv.Int()))
+	case reflect.Int16:
+		enc.PackInt16(int16(rv.Int()))
+	case reflect.Int32, reflect.Int:
+		if tag.varint {
+			enc.PackVarInt32(int32(rv.Int()))
+		} else if rv.Kind() == reflect.Int32 {
+			enc.PackInt32(int32(rv.Int()))
+		} else {
+			return newErrorf("int has to be tagged with varint32")
+		}
+	case reflect.Int64:
+		enc.PackInt64(rv.Int())
+	case reflect.Uint8:
+		enc.PackUint8(uint8(rv.Uint()))
+	case reflect.Uint16:
+		enc.PackUint16(uint16(rv.Uint()))
+	case reflect.Uint32, reflect.Uint:
+		if tag.varuint {
+			enc.PackVarUint32(uint32(rv.Uint()))
+		} else if rv.Kind() == reflect.Uint32 {
+			enc.PackUint32(uint32(rv.Uint()))
+		} else {
+			return newErrorf("uint has to be tagged with varuint32")
+		}
+	case reflect.Uint64:
+		enc.PackUint64(rv.Uint())
+	case reflect.Float32:
+		enc.PackFloat32(float32(rv.Float()))
+	case reflect.Float64:
+		enc.PackFloat64(rv.Float())
+	case reflect.String:
+		enc.PackString(rv.String())
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			enc.PackBytes(rv.Bytes())
+			return nil
+		}
+		enc.PackLength(rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			if err := enc.packReflectElem(rv.Index(i), tag.elemTag()); err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if err := enc.packReflectElem(rv.Index(i), tag.elemTag()); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		fields, err := getReflectFields(rv.Type())
+		if err != nil {
+			return err
+		}
+		for _, field := range fields {
+			value := rv.Field(field.index)
+			if field.tag.extension && !field.tag.optional && value.Kind() == reflect.Ptr && value.IsNil() {
+				break
+			}
+			if err := enc.packReflectElem(value, field.tag); err != nil {
+				return newErrorf("%s.%s: %v", rv.Type().Name(), field.name, err)
+			}
+		}
+	default:
+		return newErrorf("unsupported type %s", rv.Type())
+	}
+	return nil
+}
+
+// packReflectElem packs the fields of structs and the elements of slices and arrays, where pointers are optionals
+func (enc *Encoder) packReflectElem(rv reflect.Value, tag eosioTag) error {
+	if rv.Kind() != reflect.Ptr || tag.extension && !tag.optional {
+		return enc.packReflectValue(rv, tag)
+	}
+
+	if rv.IsNil() {
+		enc.PackBool(false)
+		return nil
+	}
+	enc.PackBool(true)
+	return enc.packReflectValue(rv.Elem(), tag)
+}
+
+// unpackReflectValue unpacks to rv, which has to be addressable
+func (dec *Decoder) unpackReflectValue(rv reflect.Value, tag eosioTag) error {
+	if rv.Kind() != reflect.Ptr && rv.Addr().Type().Implements(unpackerType) {
+		n, err := rv.Addr().Interface().(Unpacker).Unpack(dec.Remains())
+		if err != nil {
+			return err
+		}
+		dec.incPos(n)
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return dec.unpackReflectValue(rv.Elem(), tag)
+	case reflect.Bool:
+		v, err := dec.UnpackBool()
+		if err != nil {
+			return err
+		}
+		rv.SetBool(v)
+	case reflect.Int8:
+		v, err := dec.UnpackInt8()
+		if err != nil {
+			return err
+		}
+		rv.SetInt(int64(v))
+	case reflect.Int16:
+		v, err := dec.UnpackInt16()
+		if err != nil {
+			return err
+		}
+		rv.SetInt(int64(v))
+	case reflect.Int32, reflect.Int:
+		var v int32
+		var err error
+		if tag.varint {
+			v, err = dec.UnpackVarInt32()
+		} else if rv.Kind() == reflect.Int32 {
+			v, err = dec.UnpackInt32()
+		} else {
+			return newErrorf("int has to be tagged with varint32")
+		}
+		if err != nil {
+			return err
+		}
+		rv.SetInt(int64(v))
+	case reflect.Int64:
+		v, err := dec.UnpackInt64()
+		if err != nil {
+			return err
+		}
+		rv.SetInt(v)
+	case reflect.Uint8:
+		v, err := dec.UnpackUint8()
+		if err != nil {
+			return err
+		}
+		rv.SetUint(uint64(v))
+	case reflect.Uint16:
+		v, err := dec.UnpackUint16()
+		if err != nil {
+			return err
+		}
+		rv.SetUint(uint64(v))
+	case reflect.Uint32, reflect.Uint:
+		var v uint32
+		if tag.varuint {
+			n, err := dec.UnpackVarUint32()
+			if err != nil {
+				return err
+			}
+			v = uint32(n)
+		} else if rv.Kind() == reflect.Uint32 {
+			n, err := dec.UnpackUint32()
+			if err != nil {
+				return err
+			}
+			v = n
+		} else {
+			return newErrorf("uint has to be tagged with varuint32")
+		}
+		rv.SetUint(uint64(v))
+	case reflect.Uint64:
+		v, err := dec.UnpackUint64()
+		if err != nil {
+			return err
+		}
+		rv.SetUint(v)
+	case reflect.Float32:
+		v, err := dec.UnpackFloat32()
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(float64(v))
+	case reflect.Float64:
+		v, err := dec.UnpackFloat64()
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(v)
+	case reflect.String:
+		v, err := dec.UnpackString()
+		if err != nil {
+			return err
+		}
+		rv.SetString(v)
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			v, err := dec.UnpackBytes()
+			if err != nil {
+				return err
+			}
+			rv.SetBytes(append([]byte{}, v...))
+			return nil
+		}
+		count, err := dec.UnpackLength()
+		if err != nil {
+			return err
+		}
+		s := reflect.MakeSlice(rv.Type(), 0, arrayCapacity(dec, count))
+		elem := reflect.New(rv.Type().Elem()).Elem()
+		for i := 0; i < count; i++ {
+			elem.Set(reflect.Zero(elem.Type()))
+			if err := dec.unpackReflectElem(elem, tag.elemTag()); err != nil {
+				return err
+			}
+			s = reflect.Append(s, elem)
+		}
+		rv.Set(s)
+	case reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if err := dec.unpackReflectElem(rv.Index(i), tag.elemTag()); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		fields, err := getReflectFields(rv.Type())
+		if err != nil {
+			return err
+		}
+		for _, field := range fields {
+			if field.tag.extension && dec.IsEnd() {
+				break
+			}
+			if err := dec.unpackReflectElem(rv.Field(field.index), field.tag); err != nil {
+				return newErrorf("%s.%s: %v", rv.Type().Name(), field.name, err)
+			}
+		}
+	default:
+		return newErrorf("unsupported type %s", rv.Type())
+	}
+	return nil
+}
+
+// unpackReflectElem unpacks the fields of structs and the elements of slices and arrays, where pointers are optionals
+func (dec *Decoder) unpackReflectElem(rv reflect.Value, tag eosioTag) error {
+	if rv.Kind() != reflect.Ptr || tag.extension && !tag.optional {
+		return dec.unpackReflectValue(rv, tag)
+	}
+
+	present, err := dec.UnpackBool()
+	if err != nil {
+		return err
+	}
+	if !present {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+	return dec.unpackReflectValue(rv, tag)
+}
@@ -0,0 +1,176 @@
+package uuoskit
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testReflectPoint struct {
+	X int32
+	Y int32
+}
+
+type testReflectRecord struct {
+	From     Name
+	Quantity Asset
+	Memo     string
+	Count    uint32 `eosio:"varuint32"`
+	Delta    int    `eosio:"varint32"`
+	Ids      []uint64
+	Sizes    []uint32 `eosio:"varuint32"`
+	Hash     [4]byte
+	Points   [2]testReflectPoint
+	Parent   *testReflectPoint
+	Note     *string `eosio:"optional"`
+	Data     []byte
+	cache    int
+	Skipped  string `eosio:"-"`
+	Referrer *Name  `eosio:"binary_extension"`
+}
+
+var testReflectAbi = `{
+	"version": "eosio::abi/1.1",
+	"structs": [
+		{"name": "point", "base": "", "fields": [{"name": "x", "type": "int32"}, {"name": "y", "type": "int32"}]},
+		{"name": "record", "base": "", "fields": [
+			{"name": "from", "type": "name"},
+			{"name": "quantity", "type": "asset"},
+			{"name": "memo", "type": "string"},
+			{"name": "count", "type": "varuint32"},
+			{"name": "delta", "type": "varint32"},
+			{"name": "ids", "type": "uint64[]"},
+			{"name": "sizes", "type": "varuint32[]"},
+			{"name": "hash", "type": "uint8[4]"},
+			{"name": "points", "type": "point[2]"},
+			{"name": "parent", "type": "point?"},
+			{"name": "note", "type": "string?"},
+			{"name": "data", "type": "bytes"},
+			{"name": "referrer", "type": "name$"}
+		]}
+	],
+	"actions": [{"name": "record", "type": "record", "ricardian_contract": ""}]
+}`
+
+func TestReflectSerializer(t *testing.T) {
+	assert := assert.New(t)
+
+	note := "hello"
+	referrer := NewName("alice")
+	record := testReflectRecord{
+		From:     NewName("hello"),
+		Quantity: *NewAsset(10000, NewSymbol("EOS", 4)),
+		Memo:     "memo",
+		Count:    300,
+		Delta:    -2,
+		Ids:      []uint64{1, 2},
+		Sizes:    []uint32{128},
+		Hash:     [4]byte{1, 2, 3, 4},
+		Points:   [2]testReflectPoint{{1, 2}, {3, 4}},
+		Note:     &note,
+		Data:     []byte{0xff},
+		cache:    1,
+		Skipped:  "skipped",
+		Referrer: &referrer,
+	}
+
+	enc := NewEncoder(64)
+	enc.Pack(record)
+	packed := enc.GetBytes()
+
+	//the same bytes as the abi serializer
+	ser := NewABISerializer()
+	err := ser.SetContractABI("test", []byte(testReflectAbi))
+	if err != nil {
+		panic(err)
+	}
+	expected, err := ser.PackActionArgs("test", "record", `{"from": "hello", "quantity": "1.0000 EOS", "memo": "memo", "count": 300, "delta": -2,
+		"ids": [1, 2], "sizes": [128], "hash": [1, 2, 3, 4], "points": [{"x": 1, "y": 2}, {"x": 3, "y": 4}], "parent": null,
+		"note": "hello", "data": "ff", "referrer": "alice"}`)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(hex.EncodeToString(expected), hex.EncodeToString(packed))
+
+	//pointers are packed as the values they point to
+	enc = NewEncoder(64)
+	enc.Pack(&record)
+	assert.Equal(packed, enc.GetBytes())
+
+	unpacked := testReflectRecord{}
+	n, err := NewDecoder(packed).Unpack(&unpacked)
+	assert.Nil(err)
+	assert.Equal(len(packed), n)
+	record.cache = 0
+	record.Skipped = ""
+	assert.Equal(record, unpacked)
+
+	//binary extensions are left out if nil
+	record.Referrer = nil
+	record.Parent = &testReflectPoint{5, 6}
+	enc = NewEncoder(64)
+	enc.Pack(record)
+	packed = enc.GetBytes()
+	unpacked = testReflectRecord{}
+	_, err = NewDecoder(packed).Unpack(&unpacked)
+	assert.Nil(err)
+	assert.Equal(record, unpacked)
+
+	//structs can be used as action data
+	action := NewAction(NewName("test"), NewName("record"), []PermissionLevel{{NewName("hello"), NewName("active")}}, record)
+	assert.Equal(packed, []byte(action.Data))
+
+	_, err = NewDecoder(packed[:len(packed)-1]).Unpack(&unpacked)
+	assert.NotNil(err)
+
+	type badTag struct {
+		A uint32 `eosio:"varuint"`
+	}
+	//nothing is written on error
+	enc = NewEncoder(8)
+	err = enc.Pack(badTag{})
+	assert.Contains(err.Error(), "unknown eosio tag option varuint")
+	assert.Equal(0, len(enc.Bytes()))
+	_, err = NewDecoder([]byte{0, 0, 0, 0}).Unpack(&badTag{})
+	assert.Contains(err.Error(), "unknown eosio tag option varuint")
+
+	type badExtension struct {
+		A *uint32 `eosio:"binary_extension"`
+		B uint32
+	}
+	_, err = NewDecoder([]byte{0, 0, 0, 0}).Unpack(&badExtension{})
+	assert.Contains(err.Error(), "follows a binary extension field")
+
+	type badInt struct {
+		A int
+	}
+	assert.NotNil(NewEncoder(8).Pack(badInt{}))
+	_, err = NewActionE(NewName("test"), NewName("record"), []PermissionLevel{}, badInt{})
+	assert.Contains(err.Error(), "int has to be tagged with varint32")
+	assert.Panics(func() { NewAction(NewName("test"), NewName("record"), []PermissionLevel{}, badInt{}) })
+
+	_, err = NewDecoder([]byte{0}).Unpack(badInt{})
+	assert.Contains(err.Error(), "unknown Unpack type")
+
+	//structs without fields take no bytes
+	type empty struct{}
+	type empties struct {
+		A []empty
+	}
+	enc = NewEncoder(8)
+	assert.Nil(enc.Pack(empties{[]empty{{}, {}, {}}}))
+	assert.Equal([]byte{3}, enc.GetBytes())
+	unpackedEmpties := empties{}
+	n, err = NewDecoder(enc.GetBytes()).Unpack(&unpackedEmpties)
+	assert.Nil(err)
+	assert.Equal(1, n)
+	assert.Equal(3, len(unpackedEmpties.A))
+
+	//huge lengths do not allocate more than the remaining bytes
+	type ids struct {
+		A []uint64
+	}
+	_, err = NewDecoder([]byte{0xff, 0xff, 0xff, 0xff, 0x0f}).Unpack(&ids{})
+	assert.NotNil(err)
+}
@@ -100,6 +100,30 @@ func (r *Rpc) GetAccount(args *GetAccountArgs) (JsonValue, error) {
 	return result2, nil
 }
 
+// GetBlockId returns the id of the block with blockNum, get_block is used if the node does not support get_block_info
+func (r *Rpc) GetBlockId(blockNum int64) (string, error) {
+	args := map[string]interface{}{"block_num": blockNum}
+	result := struct {
+		Id string `json:"id"`
+	}{}
+
+	for _, endpoint := range []string{"get_block_info", "get_block"} {
+		if endpoint == "get_block" {
+			args = map[string]interface{}{"block_num_or_id": blockNum}
+		}
+
+		r, err := r.Call("chain", endpoint, args)
+		if err != nil {
+			return "", newError(err)
+		}
+
+		if err := json.Unmarshal(r, &result); err == nil && len(result.Id) == 64 {
+			return result.Id, nil
+		}
+	}
+	return "", newErrorf("can not get id of block %d", blockNum)
+}
+
 func (r *Rpc) GetRequiredKeys(args *GetRequiredKeysArgs) (*GetRequiredKeysResult, error) {
 	_args, err := json.Marshal(args)
 	if err != nil {
@@ -151,6 +175,57 @@ func (t *Rpc) PushTransaction(packedTx *PackedTransaction) (JsonValue, error) {
 	return result, nil
 }
 
+// SendTransaction pushes packedTx with /v1/chain/send_transaction, which is supported since EOSIO 2.0
+func (t *Rpc) SendTransaction(packedTx *PackedTransaction) (JsonValue, error) {
+	return t.sendTransaction("send_transaction", packedTx)
+}
+
+// SendTransactionOptions are the options of /v1/chain/send_transaction2
+type SendTransactionOptions struct {
+	//return the trace of a failed transaction instead of an error
+	ReturnFailureTrace bool `json:"return_failure_trace"`
+	//let the node resend the transaction until it is irreversible or expired,
+	//requires transaction-retry-max-storage-size-gb on the node
+	RetryTrx bool `json:"retry_trx"`
+	//with RetryTrx, wait until the transaction is this number of blocks deep instead of irreversible
+	RetryTrxNumBlocks uint16 `json:"retry_trx_num_blocks,omitempty"`
+}
+
+// SendTransaction2 pushes packedTx with /v1/chain/send_transaction2, which is supported since Leap 3.1
+func (t *Rpc) SendTransaction2(packedTx *PackedTransaction, options *SendTransactionOptions) (JsonValue, error) {
+	args := struct {
+		*SendTransactionOptions
+		Transaction *PackedTransaction `json:"transaction"`
+	}{options, packedTx}
+	if args.SendTransactionOptions == nil {
+		args.SendTransactionOptions = &SendTransactionOptions{}
+	}
+
+	result := JsonValue{}
+	r, err := t.Call("chain", "send_transaction2", &args)
+	if err != nil {
+		return JsonValue{}, err
+	}
+	err = json.Unmarshal(r, &result)
+	if err != nil {
+		return JsonValue{}, newError(err)
+	}
+	return result, nil
+}
+
+func (t *Rpc) sendTransaction(endpoint string, packedTx *PackedTransaction) (JsonValue, error) {
+	result := JsonValue{}
+	r, err := t.Call("chain", endpoint, packedTx)
+	if err != nil {
+		return JsonValue{}, err
+	}
+	err = json.Unmarshal(r, &result)
+	if err != nil {
+		return JsonValue{}, newError(err)
+	}
+	return result, nil
+}
+
 func (r *Rpc) Call(api string, endpoint string, params interface{}) ([]byte, error) {
 	var _params []byte
 	reqUrl := fmt.Sprintf("%s/v1/%s/%s", r.url, api, endpoint)
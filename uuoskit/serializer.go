@@ -2,8 +2,8 @@ package uuoskit
 
 import (
 	"encoding/binary"
-	"fmt"
 	"math"
+	"reflect"
 	"unsafe"
 )
 
@@ -272,18 +272,27 @@ func (dec *Decoder) UnpackBytes() ([]byte, error) {
 }
 
 func (dec *Decoder) UnpackLength() (int, error) {
+	if err := dec.checkPos(1); err != nil {
+		return 0, err
+	}
 	v, n := UnpackVarUint32(dec.buf[dec.pos:])
 	dec.incPos(n)
 	return int(v), nil
 }
 
 func (dec *Decoder) UnpackVarInt32() (int32, error) {
+	if err := dec.checkPos(1); err != nil {
+		return 0, err
+	}
 	v, n := UnpackVarInt32(dec.buf[dec.pos:])
 	dec.incPos(n)
 	return v, nil
 }
 
 func (dec *Decoder) UnpackVarUint32() (VarUint32, error) {
+	if err := dec.checkPos(1); err != nil {
+		return 0, err
+	}
 	v, n := UnpackVarUint32(dec.buf[dec.pos:])
 	dec.incPos(n)
 	return VarUint32(v), nil
@@ -485,7 +494,14 @@ func (dec *Decoder) Unpack(i interface{}) (n int, err error) {
 	// 	v.N = n
 	// 	return 8, nil
 	default:
-		panic(fmt.Sprintf("unknown Unpack type %v %T", v, v))
+		//pointers to structs, slices and arrays, see eosioTag
+		rv := reflect.ValueOf(i)
+		if rv.Kind() != reflect.Ptr || rv.IsNil() {
+			return 0, newErrorf("unknown Unpack type %T", v)
+		}
+		pos := dec.Pos()
+		err = dec.unpackReflectValue(rv.Elem(), eosioTag{})
+		return dec.Pos() - pos, err
 	}
 	return 0, err
 }
@@ -525,6 +541,8 @@ func (enc *Encoder) WriteByte(b byte) {
 // string, bytes
 // byte, uint16, int32, uint32, int64, uint64, float64
 // Name
+// structs, slices, arrays and pointers, see eosioTag
+// other types return an error
 func (enc *Encoder) Pack(i interface{}) error {
 	switch v := i.(type) {
 	case Packer:
@@ -566,11 +584,13 @@ func (enc *Encoder) Pack(i interface{}) error {
 	case Name:
 		enc.WriteUint64(v.N)
 	default:
-		// if DEBUG {
-		// 	panic(fmt.Sprintf("Unknow Pack type <%v>", i))
-		// }
-		panic("Unknown Pack type")
-		//		return errors.New("Unknow Pack type")
+		//structs, slices, arrays and pointers, see eosioTag
+		//nothing is written on error
+		pos := len(enc.buf)
+		if err := enc.packReflectValue(reflect.ValueOf(i), eosioTag{}); err != nil {
+			enc.buf = enc.buf[:pos]
+			return newErrorf("Unknown Pack type %T: %v", i, err)
+		}
 	}
 	return nil
 }
@@ -741,6 +761,11 @@ func CalcPackedSize(i interface{}) (int, error) {
 	case Name:
 		return 8, nil
 	default:
-		return 0, newErrorf("Unknow pack type")
+		//structs, slices, arrays and pointers are measured by packing them
+		enc := NewEncoder(64)
+		if err := enc.packReflectValue(reflect.ValueOf(i), eosioTag{}); err != nil {
+			return 0, newErrorf("Unknow pack type %T: %v", i, err)
+		}
+		return len(enc.buf), nil
 	}
 }
@@ -0,0 +1,24 @@
+package uuoskit
+
+// Signer signs transaction digests with keys it holds,
+// the keys never have to leave the signer, e.g. a wallet in this process or a remote signing daemon.
+type Signer interface {
+	// GetAvailableKeys returns the public keys the signer can sign with
+	GetAvailableKeys() ([]string, error)
+	// SignDigest signs a 32 bytes digest with the private key of pubKey and returns the signature string
+	SignDigest(digest []byte, pubKey string) (string, error)
+}
+
+// GetAvailableKeys implements Signer
+func (m *WalletManager) GetAvailableKeys() ([]string, error) {
+	return m.GetPublicKeys(), nil
+}
+
+// SignDigest implements Signer
+func (m *WalletManager) SignDigest(digest []byte, pubKey string) (string, error) {
+	sig, err := m.Sign(digest, pubKey)
+	if err != nil {
+		return "", err
+	}
+	return sig.String(), nil
+}
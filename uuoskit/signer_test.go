@@ -0,0 +1,146 @@
+package uuoskit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	secp256k1 "github.com/uuosio/go-secp256k1"
+)
+
+type testSigner struct {
+	keys   map[string]*secp256k1.PrivateKey
+	signed []string
+}
+
+func newTestSigner(privKeys ...string) *testSigner {
+	s := &testSigner{keys: make(map[string]*secp256k1.PrivateKey)}
+	for _, strPriv := range privKeys {
+		priv, err := secp256k1.NewPrivateKeyFromBase58(strPriv)
+		if err != nil {
+			panic(err)
+		}
+		s.keys[priv.GetPublicKey().StringEOS()] = priv
+	}
+	return s
+}
+
+func (s *testSigner) GetAvailableKeys() ([]string, error) {
+	keys := []string{}
+	for k := range s.keys {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (s *testSigner) SignDigest(digest []byte, pubKey string) (string, error) {
+	priv, ok := s.keys[pubKey]
+	if !ok {
+		return "", newErrorf("not found")
+	}
+	sig, err := priv.Sign(digest)
+	if err != nil {
+		return "", err
+	}
+	s.signed = append(s.signed, pubKey)
+	return sig.String(), nil
+}
+
+func newTestNode(t *testing.T, handlers map[string]func(body []byte) interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := handlers[r.URL.Path]
+		if !ok {
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		result, _ := json.Marshal(handler(body))
+		w.Write(result)
+	}))
+}
+
+var testChainInfo = map[string]interface{}{
+	"chain_id":                    "9b1605a3f7f14995641c6b19413841c26ca86747f054241951a298b556160674",
+	"head_block_num":              5918917,
+	"last_irreversible_block_num": 5918916,
+	"last_irreversible_block_id":  "005a50c451107fd4d94493f152d832a6420aa7945d51974dca56b2a1f3dfe5fe",
+	"head_block_id":               "005a50c54ce7a3c4859681ebd3aea624befefaa219c70391fb43ac9453d9cdce",
+	"server_version_string":       "v2.1.0-rc1",
+}
+
+func TestPackedTransactionSignWithSigner(t *testing.T) {
+	secp256k1.Init()
+	assert := assert.New(t)
+
+	pub := "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"
+	signer := newTestSigner("5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL")
+
+	tx := NewTransaction(1122)
+	tx.AddAction(NewAction(NewName("hello"), NewName("sayhello"), []PermissionLevel{{NewName("hello"), NewName("active")}}, "hello"))
+	packedTx := NewPackedTransaction(tx)
+	_, err := packedTx.SignWithSigner(signer, pub)
+	assert.NotNil(err, "sign without chain id")
+
+	chainId := testChainInfo["chain_id"].(string)
+	packedTx.SetChainId(chainId)
+	sig, err := packedTx.SignWithSigner(signer, pub)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal([]string{sig}, packedTx.Signatures)
+
+	//the signer signs the same digest as signing with the private key directly
+	sig2, err := tx.Sign("5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL", chainId)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(sig2, sig)
+
+	//signing twice does not add a duplicated signature
+	sig, err = packedTx.SignWithSigner(signer, pub)
+	assert.Nil(err)
+	assert.Equal("", sig)
+	assert.Equal(1, len(packedTx.Signatures))
+}
+
+func TestChainApiSigner(t *testing.T) {
+	secp256k1.Init()
+	assert := assert.New(t)
+
+	pub := "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"
+	signer := newTestSigner("5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL")
+
+	var pushed PackedTransaction
+	node := newTestNode(t, map[string]func(body []byte) interface{}{
+		"/v1/chain/get_info": func(body []byte) interface{} {
+			return testChainInfo
+		},
+		"/v1/chain/get_required_keys": func(body []byte) interface{} {
+			args := map[string]interface{}{}
+			json.Unmarshal(body, &args)
+			assert.Equal([]interface{}{pub}, args["available_keys"])
+			return GetRequiredKeysResult{RequiredKeys: []string{pub}}
+		},
+		"/v1/chain/send_transaction": func(body []byte) interface{} {
+			json.Unmarshal(body, &pushed)
+			return map[string]interface{}{"transaction_id": "00"}
+		},
+	})
+	defer node.Close()
+
+	api := NewChainApi(node.URL)
+	assert.Equal(GetWallet(), api.GetSigner())
+	api.SetSigner(signer)
+
+	action := NewAction(NewName("hello"), NewName("sayhello"), []PermissionLevel{{NewName("hello"), NewName("active")}}, "hello")
+	_, err := api.PushAction(action)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal([]string{pub}, signer.signed)
+	assert.Equal(1, len(pushed.Signatures))
+}
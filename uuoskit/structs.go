@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"math/big"
 	"strconv"
 	"strings"
 	"time"
@@ -96,6 +97,28 @@ func (t *Uint128) Size() int {
 	return 16
 }
 
+// MarshalJSON renders the value as 0x followed by the big endian hex string, like the abi serializer does
+func (n Uint128) MarshalJSON() ([]byte, error) {
+	buf := n
+	reverseBytes(buf[:])
+	return json.Marshal("0x" + hex.EncodeToString(buf[:]))
+}
+
+// UnmarshalJSON accepts 0x prefixed hex strings and decimal numbers
+func (n *Uint128) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	v, ok := new(big.Int).SetString(s, 0)
+	if !ok || v.Sign() < 0 || v.BitLen() > 128 {
+		return newErrorf("invalid uint128 value: %s", string(b))
+	}
+
+	buf := [16]byte{}
+	v.FillBytes(buf[:])
+	reverseBytes(buf[:])
+	*n = buf
+	return nil
+}
+
 func (n *Uint128) SetUint64(v uint64) {
 	tmp := Uint128{}
 	copy(n[:], tmp[:]) //memset
@@ -226,6 +249,8 @@ func (t *BlockTimestampType) Size() int {
 	return 4
 }
 
+// JsonValue is a parsed json value, GetValue returns nil for null, bool, json.Number, string,
+// []JsonValue or map[string]JsonValue
 type JsonValue struct {
 	value interface{}
 }
@@ -242,36 +267,54 @@ func (b *JsonValue) GetValue() interface{} {
 
 func (b *JsonValue) SetValue(value interface{}) error {
 	switch value.(type) {
-	case string, []JsonValue, map[string]JsonValue:
+	case nil, bool, json.Number, string, []JsonValue, map[string]JsonValue:
 		b.value = value
 		return nil
 	default:
-		panic("value must be a string, slice, or map")
+		panic("value must be nil, a bool, a number, a string, a slice, or a map")
 	}
 	return nil
 }
 
-func parseSubValue(subValue JsonValue) interface{} {
-	switch v := subValue.value.(type) {
-	case string:
-		return strings.Trim(v, "\"")
-	case []JsonValue, map[string]JsonValue:
-		return subValue.value
-	}
-	return subValue
+func (b *JsonValue) IsNull() bool {
+	return b.value == nil
+}
+
+func (b *JsonValue) GetBoolValue() (bool, bool) {
+	v, ok := b.value.(bool)
+	return v, ok
+}
+
+func (b *JsonValue) GetNumberValue() (json.Number, bool) {
+	v, ok := b.value.(json.Number)
+	return v, ok
 }
 
 func (b *JsonValue) GetStringValue() (string, bool) {
-	switch v := b.value.(type) {
-	case string:
-		return strings.Trim(v, "\""), true
-	default:
-		return "", false
-	}
+	v, ok := b.value.(string)
+	return v, ok
 }
 
-//return string, []JsonValue, or map[string]JsonValue
+//return string, []JsonValue, or map[string]JsonValue, nulls, bools and numbers are returned as their text
 func (b *JsonValue) Get(keys ...interface{}) (interface{}, error) {
+	value, err := b.GetTyped(keys...)
+	if err != nil {
+		return value, err
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "null", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case json.Number:
+		return string(v), nil
+	}
+	return value, nil
+}
+
+//return nil, bool, json.Number, string, []JsonValue, or map[string]JsonValue
+func (b *JsonValue) GetTyped(keys ...interface{}) (interface{}, error) {
 	if len(keys) == 0 {
 		return JsonValue{}, newErrorf("no key specified")
 	}
@@ -286,7 +329,7 @@ func (b *JsonValue) Get(keys ...interface{}) (interface{}, error) {
 				if !ok {
 					return nil, newErrorf("key not found")
 				}
-				value = parseSubValue(subValue)
+				value = subValue.value
 			case JsonValue:
 				v3, ok := v2.value.(map[string]JsonValue)
 				if !ok {
@@ -296,7 +339,7 @@ func (b *JsonValue) Get(keys ...interface{}) (interface{}, error) {
 				if !ok {
 					return nil, newErrorf("key not found")
 				}
-				value = parseSubValue(subValue)
+				value = subValue.value
 			default:
 				return JsonValue{}, newErrorf("2:JsonValue is not a map")
 			}
@@ -325,17 +368,25 @@ func (b *JsonValue) Get(keys ...interface{}) (interface{}, error) {
 	return value, nil
 }
 
+//return the text of strings, numbers and bools
 func (b *JsonValue) GetString(keys ...interface{}) (string, error) {
-	v, err := b.Get(keys...)
+	v, err := b.GetTyped(keys...)
 	if err != nil {
 		return "", err
 	}
 
-	_v, ok := v.(string)
-	if !ok {
-		return "", newErrorf("value is not a string")
+	if jv, ok := v.(JsonValue); ok {
+		v = jv.value
+	}
+	switch _v := v.(type) {
+	case string:
+		return _v, nil
+	case json.Number:
+		return string(_v), nil
+	case bool:
+		return strconv.FormatBool(_v), nil
 	}
-	return strings.Trim(_v, "\""), nil
+	return "", newErrorf("value is not a string")
 }
 
 func (b *JsonValue) GetTime(keys ...interface{}) (*time.Time, error) {
@@ -352,44 +403,38 @@ func (b *JsonValue) GetTime(keys ...interface{}) (*time.Time, error) {
 }
 
 func (b JsonValue) MarshalJSON() ([]byte, error) {
-	switch v := b.value.(type) {
-	case string:
-		if _, err := strconv.ParseInt(v, 10, 64); err == nil {
-			return []byte(v), nil
-		} else {
-			return []byte(strconv.Quote(v)), nil
-		}
-	case JsonValue:
-		if s, ok := v.value.(string); ok {
-			return []byte(s), nil
-		}
-		return json.Marshal(v.value)
-	case map[string]JsonValue:
-		return json.Marshal(v)
-	case []JsonValue:
-		return json.Marshal(v)
-	}
-	return nil, newErrorf("bad JsonValue")
+	return json.Marshal(b.value)
 }
 
 func (b *JsonValue) UnmarshalJSON(data []byte) error {
-	// fmt.Println("+++++:UnmarshaJSON", string(data))
-	if data[0] == '{' {
+	switch data[0] {
+	case '{':
 		m := make(map[string]JsonValue)
 		err := json.Unmarshal(data, &m)
 		if err != nil {
 			return newError(err)
 		}
 		b.value = m
-	} else if data[0] == '[' {
+	case '[':
 		m := make([]JsonValue, 0, 1)
 		err := json.Unmarshal(data, &m)
 		if err != nil {
 			return newError(err)
 		}
 		b.value = m
-	} else {
-		b.value = string(data)
+	case '"':
+		var v string
+		err := json.Unmarshal(data, &v)
+		if err != nil {
+			return newError(err)
+		}
+		b.value = v
+	case 't', 'f':
+		b.value = data[0] == 't'
+	case 'n':
+		b.value = nil
+	default:
+		b.value = json.Number(data)
 	}
 	return nil
 }
@@ -15,7 +15,21 @@ func Check(cond bool, msg string) {
 }
 
 func NewSymbol(name string, precision int) Symbol {
-	Check(len(name) <= 7, "bad symbol name")
+	sym, err := NewSymbolE(name, precision)
+	if err != nil {
+		panic(err.Error())
+	}
+	return sym
+}
+
+// NewSymbolE checks the length of name and the precision
+func NewSymbolE(name string, precision int) (Symbol, error) {
+	if len(name) > 7 {
+		return Symbol{}, newErrorf("bad symbol name")
+	}
+	if precision < 0 || precision > 0xff {
+		return Symbol{}, newErrorf("bad symbol precision %d", precision)
+	}
 	value := uint64(0)
 	for i := range name {
 		v := name[len(name)-1-i]
@@ -23,7 +37,7 @@ func NewSymbol(name string, precision int) Symbol {
 		value <<= 8
 	}
 	value |= uint64(precision)
-	return Symbol{value}
+	return Symbol{value}, nil
 }
 
 func (a *Symbol) Code() uint64 {
@@ -77,50 +91,112 @@ func isAmountWithInRange(amount int64) bool {
 }
 
 func NewAsset(amount int64, symbol Symbol) *Asset {
-	Check(symbol.IsValid(), "bad symbol")
-	a := &Asset{amount, symbol}
-	Check(isAmountWithInRange(amount), "magnitude of asset amount must be less than 2^62")
+	return mustAsset(NewAssetE(amount, symbol))
+}
+
+// NewAssetE checks symbol and the range of amount
+func NewAssetE(amount int64, symbol Symbol) (*Asset, error) {
+	if !symbol.IsValid() {
+		return nil, newErrorf("bad symbol")
+	}
+	if !isAmountWithInRange(amount) {
+		return nil, newErrorf("magnitude of asset amount must be less than 2^62")
+	}
+	return &Asset{amount, symbol}, nil
+}
+
+func mustAsset(a *Asset, err error) *Asset {
+	if err != nil {
+		panic(err.Error())
+	}
 	return a
 }
 
 func (a *Asset) Add(b *Asset) *Asset {
-	Check(a.Symbol == b.Symbol, "Asset.Add:Symbol not the same")
-	a.Amount += b.Amount
-	Check(-MAX_AMOUNT <= a.Amount, "addition underflow")
-	Check(a.Amount <= MAX_AMOUNT, "addition overflow")
-	return a
+	return mustAsset(a.AddE(b))
 }
 
 func (a *Asset) Sub(b *Asset) *Asset {
-	Check(a.Symbol == b.Symbol, "Asset.Sub:Symbol not the same")
-	a.Amount -= b.Amount
-	Check(a.Amount >= -MAX_AMOUNT, "subtraction underflow")
-	Check(a.Amount <= MAX_AMOUNT, "subtraction overflow")
-	return a
+	return mustAsset(a.SubE(b))
 }
 
 func (a *Asset) Mul(b *Asset) *Asset {
-	Check(a.Symbol == b.Symbol, "Asset.Mul:Symbol not the same")
+	return mustAsset(a.MulE(b))
+}
+
+func (a *Asset) Div(b *Asset) *Asset {
+	return mustAsset(a.DivE(b))
+}
+
+// AddE adds b to a if they have the same symbol and the sum is in range
+func (a *Asset) AddE(b *Asset) (*Asset, error) {
+	if a.Symbol != b.Symbol {
+		return nil, newErrorf("Asset.Add:Symbol not the same")
+	}
+	//amounts in range can not overflow int64
+	amount := a.Amount + b.Amount
+	if amount < -MAX_AMOUNT {
+		return nil, newErrorf("addition underflow")
+	}
+	if amount > MAX_AMOUNT {
+		return nil, newErrorf("addition overflow")
+	}
+	a.Amount = amount
+	return a, nil
+}
+
+// SubE subtracts b from a, see AddE
+func (a *Asset) SubE(b *Asset) (*Asset, error) {
+	if a.Symbol != b.Symbol {
+		return nil, newErrorf("Asset.Sub:Symbol not the same")
+	}
+	amount := a.Amount - b.Amount
+	if amount < -MAX_AMOUNT {
+		return nil, newErrorf("subtraction underflow")
+	}
+	if amount > MAX_AMOUNT {
+		return nil, newErrorf("subtraction overflow")
+	}
+	a.Amount = amount
+	return a, nil
+}
+
+// MulE multiplies a by b, see AddE
+func (a *Asset) MulE(b *Asset) (*Asset, error) {
+	if a.Symbol != b.Symbol {
+		return nil, newErrorf("Asset.Mul:Symbol not the same")
+	}
 	_a := big.NewInt(a.Amount)
 	_b := big.NewInt(b.Amount)
 	_z := big.NewInt(0)
 	_z.Mul(_a, _b)
 
 	m := big.NewInt(MAX_AMOUNT)
-	Check(m.Cmp(_z) >= 0, "multiplication overflow")
+	if m.Cmp(_z) < 0 {
+		return nil, newErrorf("multiplication overflow")
+	}
 
 	m = big.NewInt(-MAX_AMOUNT)
-	Check(_z.Cmp(m) >= 0, "multiplication underflow")
+	if _z.Cmp(m) < 0 {
+		return nil, newErrorf("multiplication underflow")
+	}
 	a.Amount = _z.Int64()
-	return a
+	return a, nil
 }
 
-func (a *Asset) Div(b *Asset) *Asset {
-	Check(a.Symbol == b.Symbol, "Asset.Mul:Symbol not the same")
-	Check(b.Amount != 0, "divide by zero")
-	Check(!(a.Amount == int64(-9223372036854775808) && b.Amount == -1), "signed division overflow")
+// DivE divides a by b, b can not be zero
+func (a *Asset) DivE(b *Asset) (*Asset, error) {
+	if a.Symbol != b.Symbol {
+		return nil, newErrorf("Asset.Div:Symbol not the same")
+	}
+	if b.Amount == 0 {
+		return nil, newErrorf("divide by zero")
+	}
+	if a.Amount == int64(-9223372036854775808) && b.Amount == -1 {
+		return nil, newErrorf("signed division overflow")
+	}
 	a.Amount /= b.Amount
-	return a
+	return a, nil
 }
 
 func (a *Asset) IsValid() bool {
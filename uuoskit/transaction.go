@@ -6,10 +6,13 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
-
-	secp256k1 "github.com/uuosio/go-secp256k1"
+	"io"
+	"io/ioutil"
 )
 
+// maxDecompressedSize is the upper limit of an inflated packed_trx or packed_context_free_data
+const maxDecompressedSize = 10 * 1024 * 1024
+
 type TransactionExtension struct {
 	Type uint16
 	Data []byte
@@ -61,10 +64,19 @@ type Transaction struct {
 	Extention          []TransactionExtension `json:"transaction_extensions"`
 }
 
+// SignedTransaction is the signed_transaction json format, context free data is kept unpacked
+type SignedTransaction struct {
+	*Transaction
+	Signatures      []string `json:"signatures"`
+	ContextFreeData []Bytes  `json:"context_free_data"`
+}
+
 type PackedTransaction struct {
-	chainId       [32]byte
-	tx            *Transaction
-	compressed    bool
+	chainId         [32]byte
+	tx              *Transaction
+	contextFreeData []Bytes
+	//uncompressed serialization of tx, digests are always calculated from it
+	rawTx         []byte
 	Signatures    []string `json:"signatures"`
 	Compression   string   `json:"compression"`
 	PackedContext Bytes    `json:"packed_context_free_data"`
@@ -108,6 +120,10 @@ func (t *Transaction) AddAction(a *Action) {
 	t.Actions = append(t.Actions, *a)
 }
 
+func (t *Transaction) AddContextFreeAction(a *Action) {
+	t.ContextFreeActions = append(t.ContextFreeActions, *a)
+}
+
 func (t *Transaction) Pack() []byte {
 	initSize := 4 + 2 + 4 + 5 + 1 + 5
 
@@ -238,19 +254,13 @@ func (t *Transaction) Sign(privKey string, chainId string) (string, error) {
 		return "", newErrorf("chainId must be 32 bytes")
 	}
 
-	hash := sha256.New()
-	hash.Write(_chainId)
-	hash.Write(t.Pack())
-	//TODO: hash context_free_data
-	cfdHash := [32]byte{}
-	hash.Write(cfdHash[:])
-	digest := hash.Sum(nil)
+	digest := calcDigest(_chainId, t.Pack(), nil)
 
-	priv, err := secp256k1.NewPrivateKeyFromBase58(privKey)
+	priv, err := NewPrivateKeyFromString(privKey)
 	if err != nil {
 		return "", err
 	}
-	sign, err := secp256k1.Sign(digest, priv)
+	sign, err := priv.Sign(digest)
 	if err != nil {
 		return "", err
 	}
@@ -266,14 +276,100 @@ func (t *Transaction) Digest(chainId string) (string, error) {
 		return "", newErrorf("chainId must be 32 bytes")
 	}
 
-	hash := sha256.New()
-	hash.Write(_chainId)
-	hash.Write(t.Pack())
-	//TODO: hash context_free_data
+	digest := calcDigest(_chainId, t.Pack(), nil)
+	return hex.EncodeToString(digest), nil
+}
+
+// packContextFreeData packs context free data as vector<bytes>,
+// an empty slice is returned if there is no context free data
+func packContextFreeData(contextFreeData []Bytes) []byte {
+	if len(contextFreeData) == 0 {
+		return nil
+	}
+
+	size := 5
+	for _, data := range contextFreeData {
+		size += 5 + len(data)
+	}
+	enc := NewEncoder(size)
+	enc.PackLength(len(contextFreeData))
+	for _, data := range contextFreeData {
+		enc.PackBytes(data)
+	}
+	return enc.GetBytes()
+}
+
+func unpackContextFreeData(packed []byte) ([]Bytes, error) {
+	if len(packed) == 0 {
+		return []Bytes{}, nil
+	}
+
+	dec := NewDecoder(packed)
+	length, err := dec.UnpackLength()
+	if err != nil {
+		return nil, err
+	}
+
+	//every entry takes at least one byte
+	if length > len(dec.Remains()) {
+		return nil, newErrorf("invalid context free data length %d", length)
+	}
+
+	contextFreeData := make([]Bytes, 0, length)
+	for i := 0; i < length; i++ {
+		data, err := dec.UnpackBytes()
+		if err != nil {
+			return nil, err
+		}
+		contextFreeData = append(contextFreeData, data)
+	}
+
+	if !dec.IsEnd() {
+		return nil, newErrorf("invalid packed context free data")
+	}
+	return contextFreeData, nil
+}
+
+func zlibCompress(data []byte) []byte {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write(data)
+	w.Close()
+	return b.Bytes()
+}
+
+func zlibDecompress(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, newError(err)
+	}
+	defer r.Close()
+
+	//limit the inflated size to guard against zlib bombs
+	buf, err := ioutil.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+	if err != nil {
+		return nil, newError(err)
+	}
+
+	if len(buf) > maxDecompressedSize {
+		return nil, newErrorf("decompressed data exceeds %d bytes", maxDecompressedSize)
+	}
+	return buf, nil
+}
+
+// calcDigest returns sha256(chain_id + packed_trx + context_free_data_hash),
+// the hash of empty context free data is 32 zero bytes
+func calcDigest(chainId []byte, packedTx []byte, contextFreeData []Bytes) []byte {
 	cfdHash := [32]byte{}
+	if len(contextFreeData) > 0 {
+		cfdHash = sha256.Sum256(packContextFreeData(contextFreeData))
+	}
+
+	hash := sha256.New()
+	hash.Write(chainId)
+	hash.Write(packedTx)
 	hash.Write(cfdHash[:])
-	digest := hash.Sum(nil)
-	return hex.EncodeToString(digest), nil
+	return hash.Sum(nil)
 }
 
 func NewPackedTransaction(tx *Transaction) *PackedTransaction {
@@ -281,19 +377,96 @@ func NewPackedTransaction(tx *Transaction) *PackedTransaction {
 	packed.Compression = "none"
 	packed.PackedTx = nil
 	packed.tx = tx
+	packed.contextFreeData = []Bytes{}
 	packed.Signatures = []string{}
 	return packed
 }
 
+// NewPackedTransactionFromString parses a transaction or signed transaction json,
+// packed transactions with a packed_trx field are handled by UnpackPackedTransaction
 func NewPackedTransactionFromString(tx string) (*PackedTransaction, error) {
+	packedTrx := struct {
+		PackedTx *json.RawMessage `json:"packed_trx"`
+	}{}
+	if err := json.Unmarshal([]byte(tx), &packedTrx); err != nil {
+		return nil, newError(err)
+	}
+	if packedTrx.PackedTx != nil {
+		return UnpackPackedTransaction(tx)
+	}
+
+	signedTx := &SignedTransaction{Transaction: &Transaction{}}
+	if err := json.Unmarshal([]byte(tx), signedTx); err != nil {
+		return nil, newError(err)
+	}
+
 	packed := &PackedTransaction{}
 	packed.Compression = "none"
 	packed.PackedTx = nil
-	packed.tx = &Transaction{}
-	if err := json.Unmarshal([]byte(tx), packed.tx); err != nil {
-		return nil, newError(err)
+	packed.tx = signedTx.Transaction
+	packed.contextFreeData = []Bytes{}
+	if len(signedTx.ContextFreeData) > 0 {
+		packed.contextFreeData = signedTx.ContextFreeData
+		packed.PackedContext = packContextFreeData(packed.contextFreeData)
 	}
 	packed.Signatures = []string{}
+	if signedTx.Signatures != nil {
+		packed.Signatures = signedTx.Signatures
+	}
+	return packed, nil
+}
+
+// UnpackPackedTransaction parses a packed transaction in the json format of
+// /v1/chain/push_transaction, e.g. the output of PackedTransaction.Pack,
+// zlib compressed packed_trx and packed_context_free_data are inflated.
+// The compression is kept, so the transaction can be signed and packed again for the next co-signer
+func UnpackPackedTransaction(packedTx string) (*PackedTransaction, error) {
+	packed := &PackedTransaction{}
+	if err := json.Unmarshal([]byte(packedTx), packed); err != nil {
+		return nil, newError(err)
+	}
+
+	rawTx := []byte(packed.PackedTx)
+	rawContext := []byte(packed.PackedContext)
+	switch packed.Compression {
+	case "none", "":
+		packed.Compression = "none"
+	case "zlib":
+		var err error
+		rawTx, err = zlibDecompress(packed.PackedTx)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(packed.PackedContext) > 0 {
+			rawContext, err = zlibDecompress(packed.PackedContext)
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, newErrorf("unsupported compression: %s", packed.Compression)
+	}
+
+	packed.tx = &Transaction{}
+	n, err := packed.tx.Unpack(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(rawTx) {
+		return nil, newErrorf("invalid packed_trx: %d bytes left", len(rawTx)-n)
+	}
+	packed.rawTx = rawTx
+
+	contextFreeData, err := unpackContextFreeData(rawContext)
+	if err != nil {
+		return nil, err
+	}
+	packed.contextFreeData = contextFreeData
+
+	if packed.Signatures == nil {
+		packed.Signatures = []string{}
+	}
 	return packed, nil
 }
 
@@ -315,67 +488,196 @@ func (t *PackedTransaction) AddAction(a *Action) error {
 	return nil
 }
 
-func (t *PackedTransaction) sign(priv *secp256k1.PrivateKey) (string, error) {
-	if t.compressed {
-		return "", newErrorf("can not sign after pack")
+func (t *PackedTransaction) AddContextFreeAction(a *Action) error {
+	if t.PackedTx != nil {
+		return newErrorf("can not add new action after pack or sign")
 	}
+	t.tx.AddContextFreeAction(a)
+	return nil
+}
 
-	if t.PackedTx == nil {
-		t.PackedTx = t.tx.Pack()
+// AddContextFreeData appends a context free data blob, which is hashed into the digest
+func (t *PackedTransaction) AddContextFreeData(data []byte) error {
+	if len(t.Signatures) > 0 {
+		return newErrorf("can not add context free data after sign")
+	}
+	t.contextFreeData = append(t.contextFreeData, Bytes(data))
+	t.PackedContext = packContextFreeData(t.contextFreeData)
+	if t.Compression == "zlib" {
+		t.PackedContext = zlibCompress(t.PackedContext)
 	}
+	return nil
+}
 
-	hash := sha256.New()
-	hash.Write(t.chainId[:])
-	hash.Write(t.PackedTx)
-	//TODO: hash context_free_data
-	cfdHash := [32]byte{}
-	hash.Write(cfdHash[:])
-	digest := hash.Sum(nil)
+// GetSignedTransaction returns the transaction with its signatures and context free data
+func (t *PackedTransaction) GetSignedTransaction() *SignedTransaction {
+	return &SignedTransaction{
+		Transaction:     t.tx,
+		Signatures:      t.Signatures,
+		ContextFreeData: t.contextFreeData,
+	}
+}
 
-	sign, err := priv.Sign(digest)
-	if err != nil {
-		return "", err
+// packTx serializes tx once, no more actions can be added after that
+func (t *PackedTransaction) packTx() []byte {
+	if t.rawTx == nil {
+		t.rawTx = t.tx.Pack()
+		t.setCompression(t.Compression == "zlib")
 	}
+	return t.rawTx
+}
 
-	newSign := sign.String()
+func (t *PackedTransaction) setCompression(compress bool) {
+	t.PackedContext = packContextFreeData(t.contextFreeData)
+	if compress {
+		t.Compression = "zlib"
+		t.PackedTx = zlibCompress(t.rawTx)
+		if len(t.PackedContext) > 0 {
+			t.PackedContext = zlibCompress(t.PackedContext)
+		}
+	} else {
+		t.Compression = "none"
+		t.PackedTx = t.rawTx
+	}
+}
+
+func (t *PackedTransaction) digest() ([]byte, error) {
+	return calcDigest(t.chainId[:], t.packTx(), t.contextFreeData), nil
+}
+
+// addSignature appends sig to Signatures, an empty string is returned if sig has already been added
+func (t *PackedTransaction) addSignature(sig string) string {
 	for i := range t.Signatures {
-		sig := t.Signatures[i]
-		if sig == newSign {
-			return "", nil
+		if t.Signatures[i] == sig {
+			return ""
 		}
 	}
+	t.Signatures = append(t.Signatures, sig)
+	return sig
+}
 
-	s := sign.String()
-	t.Signatures = append(t.Signatures, s)
-	return s, nil
+func (t *PackedTransaction) sign(priv *PrivateKey) (string, error) {
+	digest, err := t.digest()
+	if err != nil {
+		return "", err
+	}
+
+	sign, err := priv.Sign(digest)
+	if err != nil {
+		return "", err
+	}
+	return t.addSignature(sign.String()), nil
 }
 
 func (t *PackedTransaction) Digest(chainId string) (string, error) {
-	return t.tx.Digest(chainId)
+	_chainId, err := DecodeHash256(chainId)
+	if err != nil {
+		return "", newError(err)
+	}
+	digest := calcDigest(_chainId, t.tx.Pack(), t.contextFreeData)
+	return hex.EncodeToString(digest), nil
 }
 
+// Sign signs the transaction with the key of pubKey in the global wallet
 func (t *PackedTransaction) Sign(pubKey string) (string, error) {
-	priv, err := GetWallet().GetPrivateKey(pubKey)
+	return t.SignWithSigner(GetWallet(), pubKey)
+}
+
+// SignWithSigner signs the transaction with the key of pubKey held by signer
+func (t *PackedTransaction) SignWithSigner(signer Signer, pubKey string) (string, error) {
+	if t.chainId == [32]byte{} {
+		return "", newErrorf("chainId is empty")
+	}
+
+	digest, err := t.digest()
+	if err != nil {
+		return "", err
+	}
+
+	sign, err := signer.SignDigest(digest, pubKey)
 	if err != nil {
 		return "", err
 	}
-	empty := false
-	for i := 0; i < 32; i++ {
-		if t.chainId[i] != 0 {
-			empty = false
-			break
+	return t.addSignature(sign), nil
+}
+
+// VerifyResult reports the public keys recovered from the signatures of a transaction
+type VerifyResult struct {
+	Digest string `json:"digest"`
+	//public keys in the order of the signatures, K1 keys are in the EOS format
+	RecoveredKeys []string `json:"recovered_keys"`
+	//signatures of keys that have already signed the transaction, nodeos rejects them
+	DuplicatedSignatures []string `json:"duplicated_signatures"`
+	//signatures that can not be parsed or recovered
+	InvalidSignatures []string `json:"invalid_signatures"`
+}
+
+// IsValid returns true if there are no duplicated or invalid signatures
+func (r *VerifyResult) IsValid() bool {
+	return len(r.DuplicatedSignatures) == 0 && len(r.InvalidSignatures) == 0
+}
+
+// HasKey checks whether pubKey, in the EOS or PUB_K1_ format, has signed the transaction
+func (r *VerifyResult) HasKey(pubKey string) bool {
+	pub, err := NewPublicKeyFromString(pubKey)
+	if err != nil {
+		return false
+	}
+
+	pubKey = pub.StringEOS()
+	for _, key := range r.RecoveredKeys {
+		if key == pubKey {
+			return true
 		}
 	}
+	return false
+}
 
-	if empty {
-		return "", newErrorf("chainId is empty")
+// Verify recovers the public keys of Signatures from the digest of the transaction
+func (t *PackedTransaction) Verify() (*VerifyResult, error) {
+	if t.chainId == [32]byte{} {
+		return nil, newErrorf("chainId is empty")
 	}
 
-	return t.sign(priv)
+	digest, err := t.digest()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VerifyResult{
+		Digest:               hex.EncodeToString(digest),
+		RecoveredKeys:        []string{},
+		DuplicatedSignatures: []string{},
+		InvalidSignatures:    []string{},
+	}
+
+	recovered := make(map[string]bool)
+	for _, strSig := range t.Signatures {
+		sig, err := NewSignatureFromString(strSig)
+		if err != nil {
+			result.InvalidSignatures = append(result.InvalidSignatures, strSig)
+			continue
+		}
+
+		pub, err := RecoverPublicKey(digest, sig)
+		if err != nil {
+			result.InvalidSignatures = append(result.InvalidSignatures, strSig)
+			continue
+		}
+
+		pubKey := pub.StringEOS()
+		if recovered[pubKey] {
+			result.DuplicatedSignatures = append(result.DuplicatedSignatures, strSig)
+			continue
+		}
+		recovered[pubKey] = true
+		result.RecoveredKeys = append(result.RecoveredKeys, pubKey)
+	}
+	return result, nil
 }
 
 func (t *PackedTransaction) SignByPrivateKey(privKey string) (string, error) {
-	priv, err := secp256k1.NewPrivateKeyFromBase58(privKey)
+	priv, err := NewPrivateKeyFromString(privKey)
 	if err != nil {
 		return "", err
 	}
@@ -383,32 +685,72 @@ func (t *PackedTransaction) SignByPrivateKey(privKey string) (string, error) {
 	return t.sign(priv)
 }
 
+// Marshal returns the transaction json, known transaction extensions are rendered as structured json
 func (t *PackedTransaction) Marshal() string {
-	r, _ := json.Marshal(t.tx)
+	extensions := make([]*transactionExtensionJSON, 0, len(t.tx.Extention))
+	for i := range t.tx.Extention {
+		extensions = append(extensions, t.tx.Extention[i].toJSON())
+	}
+
+	tx := struct {
+		*Transaction
+		Extention []*transactionExtensionJSON `json:"transaction_extensions"`
+	}{t.tx, extensions}
+	r, _ := json.Marshal(tx)
 	return string(r)
 }
 
+// Pack returns the json of the packed transaction, signing is still possible after that
 func (t *PackedTransaction) Pack(compress bool) string {
-	if compress {
-		t.Compression = "zlib"
-	} else {
-		t.Compression = "none"
+	t.packTx()
+	if compress != (t.Compression == "zlib") {
+		t.setCompression(compress)
+	}
+
+	packed, _ := json.Marshal(t)
+	return string(packed)
+}
+
+// MergeSignatures adds the signatures of partially signed copies of the same transaction
+func (t *PackedTransaction) MergeSignatures(others ...*PackedTransaction) error {
+	rawTx := t.packTx()
+	rawContext := packContextFreeData(t.contextFreeData)
+	for _, other := range others {
+		if !bytes.Equal(rawTx, other.packTx()) {
+			return newErrorf("can not merge signatures of different transactions")
+		}
+
+		if !bytes.Equal(rawContext, packContextFreeData(other.contextFreeData)) {
+			return newErrorf("can not merge signatures of transactions with different context free data")
+		}
 	}
 
-	if t.PackedTx == nil {
-		t.PackedTx = t.tx.Pack()
+	for _, other := range others {
+		for _, sig := range other.Signatures {
+			t.addSignature(sig)
+		}
 	}
+	return nil
+}
 
-	if compress && !t.compressed {
-		//TODO: compress PackedTx with zlib
-		var b bytes.Buffer
-		w := zlib.NewWriter(&b)
-		w.Write(t.PackedTx[:])
-		w.Close()
-		t.PackedTx = b.Bytes()
-		t.compressed = true
+// MergePackedTransactions merges the signatures of packed transaction jsons,
+// the compression of the first one is kept
+func MergePackedTransactions(packedTxs ...string) (*PackedTransaction, error) {
+	if len(packedTxs) == 0 {
+		return nil, newErrorf("no transaction to merge")
 	}
 
-	packed, _ := json.Marshal(t)
-	return string(packed)
+	txs := make([]*PackedTransaction, 0, len(packedTxs))
+	for _, packedTx := range packedTxs {
+		tx, err := UnpackPackedTransaction(packedTx)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, tx)
+	}
+
+	if err := txs[0].MergeSignatures(txs[1:]...); err != nil {
+		return nil, err
+	}
+	return txs[0], nil
 }
@@ -0,0 +1,406 @@
+package uuoskit
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	secp256k1 "github.com/uuosio/go-secp256k1"
+)
+
+func newTestPackedTransaction() *PackedTransaction {
+	tx := NewTransaction(1122)
+	tx.AddAction(NewAction(NewName("hello"), NewName("sayhello"), []PermissionLevel{{NewName("hello"), NewName("active")}}, "hello"))
+	packedTx := NewPackedTransaction(tx)
+	packedTx.SetChainId(testChainInfo["chain_id"].(string))
+	return packedTx
+}
+
+func TestContextFreeData(t *testing.T) {
+	secp256k1.Init()
+	assert := assert.New(t)
+
+	chainId := testChainInfo["chain_id"].(string)
+	priv := "5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL"
+
+	packedTx := newTestPackedTransaction()
+	err := packedTx.AddContextFreeAction(NewAction(NewName("oracle"), NewName("feed"), []PermissionLevel{}, ""))
+	assert.Nil(err)
+
+	//without context free data the digest is the same as the digest of the transaction
+	digest, err := packedTx.Digest(chainId)
+	assert.Nil(err)
+	txDigest, err := packedTx.tx.Digest(chainId)
+	assert.Nil(err)
+	assert.Equal(txDigest, digest)
+
+	assert.Nil(packedTx.AddContextFreeData([]byte("hello")))
+	assert.Nil(packedTx.AddContextFreeData([]byte{}))
+	assert.Equal("020568656c6c6f00", hex.EncodeToString(packedTx.PackedContext))
+
+	_chainId, _ := hex.DecodeString(chainId)
+	cfdHash := sha256.Sum256(packedTx.PackedContext)
+	hash := sha256.New()
+	hash.Write(_chainId)
+	hash.Write(packedTx.tx.Pack())
+	hash.Write(cfdHash[:])
+	expected := hex.EncodeToString(hash.Sum(nil))
+
+	digest, err = packedTx.Digest(chainId)
+	assert.Nil(err)
+	assert.Equal(expected, digest)
+
+	sig, err := packedTx.SignByPrivateKey(priv)
+	if err != nil {
+		panic(err)
+	}
+	_digest, _ := hex.DecodeString(expected)
+	_sig, _ := NewSignatureFromString(sig)
+	pub, err := RecoverPublicKey(_digest, _sig)
+	assert.Nil(err)
+	assert.Equal("EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV", pub.StringEOS())
+
+	assert.NotNil(packedTx.AddContextFreeData([]byte("world")))
+
+	packed := packedTx.Pack(false)
+	r := map[string]interface{}{}
+	json.Unmarshal([]byte(packed), &r)
+	assert.Equal("020568656c6c6f00", r["packed_context_free_data"])
+
+	unpacked, err := UnpackPackedTransaction(packed)
+	if err != nil {
+		panic(err)
+	}
+	unpacked.SetChainId(chainId)
+	assert.Equal([]string{sig}, unpacked.Signatures)
+	digest, err = unpacked.Digest(chainId)
+	assert.Nil(err)
+	assert.Equal(expected, digest)
+
+	signedTx := unpacked.GetSignedTransaction()
+	assert.Equal([]Bytes{Bytes("hello"), Bytes{}}, signedTx.ContextFreeData)
+	assert.Equal(1, len(signedTx.ContextFreeActions))
+	assert.Equal(packedTx.tx.Pack(), signedTx.Transaction.Pack())
+
+	_, err = unpackContextFreeData([]byte{0x02, 0x01})
+	assert.NotNil(err)
+
+	_, err = unpackContextFreeData([]byte{0xff, 0xff, 0xff, 0xff, 0x0f})
+	assert.Contains(err.Error(), "invalid context free data length 4294967295")
+}
+
+func TestTransactionExtensions(t *testing.T) {
+	assert := assert.New(t)
+
+	packedTx := newTestPackedTransaction()
+	err := packedTx.SetResourcePayer(NewName("sponsor"), 1024, 2000, 0)
+	assert.Nil(err)
+
+	senderId := Uint128{}
+	senderId.SetUint64(7)
+	ext, err := NewDeferredTransactionGenerationContextExtension(testChainInfo["chain_id"].(string), senderId, NewName("hello"))
+	if err != nil {
+		panic(err)
+	}
+	assert.Nil(packedTx.AddExtension(ext))
+
+	//extensions are kept in ascending order
+	tx := packedTx.tx
+	assert.Equal(2, len(tx.Extention))
+	assert.Equal(DeferredTransactionGenerationContextId, tx.Extention[0].Type)
+	assert.Equal(ResourcePayerId, tx.Extention[1].Type)
+	assert.Nil(tx.ValidateExtensions())
+	payerName := NewName("sponsor")
+	assert.Equal(hex.EncodeToString(payerName.Pack())+"0004000000000000"+"d007000000000000"+"0000000000000000", hex.EncodeToString(tx.Extention[1].Data))
+
+	//replaces the existing resource payer
+	assert.Nil(packedTx.SetResourcePayer(NewName("sponsor"), 2048, 2000, 0))
+	assert.Equal(2, len(tx.Extention))
+	payer, err := tx.GetResourcePayer()
+	assert.Nil(err)
+	assert.Equal(&ResourcePayer{NewName("sponsor"), 2048, 2000, 0}, payer)
+
+	ctx, err := tx.GetDeferredTransactionGenerationContext()
+	assert.Nil(err)
+	assert.Equal(uint64(7), ctx.SenderId.Uint64())
+	assert.Equal("hello", ctx.Sender.String())
+
+	r := map[string]interface{}{}
+	err = json.Unmarshal([]byte(packedTx.Marshal()), &r)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(map[string]interface{}{
+		"type": float64(1),
+		"name": "resource_payer",
+		"data": map[string]interface{}{
+			"payer":            "sponsor",
+			"max_net_bytes":    float64(2048),
+			"max_cpu_us":       float64(2000),
+			"max_memory_bytes": float64(0),
+		},
+	}, r["transaction_extensions"].([]interface{})[1])
+	assert.Equal("0x00000000000000000000000000000007", r["transaction_extensions"].([]interface{})[0].(map[string]interface{})["data"].(map[string]interface{})["sender_id"])
+
+	//the structured json can be parsed back
+	packedTx2, err := NewPackedTransactionFromString(packedTx.Marshal())
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(tx.Pack(), packedTx2.tx.Pack())
+
+	//so can the pair format of nodeos
+	js, _ := json.Marshal(tx)
+	assert.Contains(string(js), `"transaction_extensions":[[0,"`)
+	packedTx2, err = NewPackedTransactionFromString(string(js))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(tx.Pack(), packedTx2.tx.Pack())
+
+	ext2 := TransactionExtension{}
+	err = json.Unmarshal([]byte(`[1, "`+hex.EncodeToString(tx.Extention[1].Data)+`"]`), &ext2)
+	assert.Nil(err)
+	assert.Equal(tx.Extention[1], ext2)
+
+	//and the base64 format of older versions
+	err = json.Unmarshal([]byte(`{"Type":1,"Data":"AAAA4FI8acUBAAAAAAAAAAIAAAAAAAAAAwAAAAAAAAA="}`), &ext2)
+	assert.Nil(err)
+	payer, err = ext2.GetResourcePayer()
+	assert.Nil(err)
+	assert.Equal(&ResourcePayer{NewName("sponsor"), 1, 2, 3}, payer)
+
+	tx.Extention[1].Data = tx.Extention[1].Data[:10]
+	assert.NotNil(tx.ValidateExtensions())
+	tx.Extention[0], tx.Extention[1] = tx.Extention[1], tx.Extention[0]
+	assert.NotNil(tx.ValidateExtensions())
+	assert.NotNil(packedTx.AddExtension(&TransactionExtension{Type: ResourcePayerId, Data: []byte{1}}))
+}
+
+func TestUnpackCompressedTransaction(t *testing.T) {
+	secp256k1.Init()
+	assert := assert.New(t)
+
+	chainId := testChainInfo["chain_id"].(string)
+	packedTx := newTestPackedTransaction()
+	assert.Nil(packedTx.AddContextFreeData([]byte("hello")))
+	assert.Nil(packedTx.SetResourcePayer(NewName("sponsor"), 1024, 2000, 0))
+	sig, err := packedTx.SignByPrivateKey("5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL")
+	if err != nil {
+		panic(err)
+	}
+	rawTx := []byte(packedTx.PackedTx)
+	rawContext := []byte(packedTx.PackedContext)
+	digest, _ := packedTx.Digest(chainId)
+
+	packed := packedTx.Pack(true)
+	assert.NotEqual(rawTx, []byte(packedTx.PackedTx))
+	assert.NotEqual(rawContext, []byte(packedTx.PackedContext))
+
+	for _, unpack := range []func(string) (*PackedTransaction, error){UnpackPackedTransaction, NewPackedTransactionFromString} {
+		unpacked, err := unpack(packed)
+		if err != nil {
+			panic(err)
+		}
+		assert.Equal(rawTx, unpacked.rawTx)
+		assert.Equal(rawContext, packContextFreeData(unpacked.contextFreeData))
+		assert.Equal("zlib", unpacked.Compression)
+		assert.Equal([]string{sig}, unpacked.Signatures)
+		_digest, err := unpacked.Digest(chainId)
+		assert.Nil(err)
+		assert.Equal(digest, _digest)
+
+		payer, err := unpacked.tx.GetResourcePayer()
+		assert.Nil(err)
+		assert.Equal("sponsor", payer.Payer.String())
+	}
+
+	//the signed transaction json round trips as well
+	unpacked, _ := UnpackPackedTransaction(packed)
+	js, _ := json.Marshal(unpacked.GetSignedTransaction())
+	unpacked, err = NewPackedTransactionFromString(string(js))
+	if err != nil {
+		panic(err)
+	}
+	_digest, _ := unpacked.Digest(chainId)
+	assert.Equal(digest, _digest)
+	assert.Equal([]string{sig}, unpacked.Signatures)
+
+	r := map[string]interface{}{}
+	json.Unmarshal([]byte(packed), &r)
+	r["packed_trx"] = "78da00"
+	bad, _ := json.Marshal(r)
+	_, err = UnpackPackedTransaction(string(bad))
+	assert.NotNil(err)
+
+	r["compression"] = "gzip"
+	bad, _ = json.Marshal(r)
+	_, err = UnpackPackedTransaction(string(bad))
+	assert.NotNil(err)
+
+	_, err = zlibDecompress(zlibCompress(make([]byte, maxDecompressedSize+1)))
+	assert.NotNil(err)
+}
+
+func TestMergeSignatures(t *testing.T) {
+	secp256k1.Init()
+	assert := assert.New(t)
+
+	chainId := testChainInfo["chain_id"].(string)
+	privKeys := []string{
+		"5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL",
+		"5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP79zkvFD3",
+	}
+
+	packedTx := newTestPackedTransaction()
+	assert.Nil(packedTx.AddContextFreeData([]byte("hello")))
+	digest, _ := packedTx.Digest(chainId)
+	packed := packedTx.Pack(true)
+
+	//signing after compression signs the digest of the uncompressed transaction
+	sig, err := packedTx.SignByPrivateKey(privKeys[0])
+	assert.Nil(err)
+	_digest, _ := hex.DecodeString(digest)
+	_sig, _ := NewSignatureFromString(sig)
+	pub, _ := RecoverPublicKey(_digest, _sig)
+	assert.Equal("EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV", pub.StringEOS())
+
+	//each co-signer signs its own copy of the compressed transaction
+	copies := []string{}
+	sigs := []string{}
+	for _, priv := range privKeys {
+		tx, err := UnpackPackedTransaction(packed)
+		if err != nil {
+			panic(err)
+		}
+		tx.SetChainId(chainId)
+		sig, err := tx.SignByPrivateKey(priv)
+		if err != nil {
+			panic(err)
+		}
+		sigs = append(sigs, sig)
+		copies = append(copies, tx.Pack(true))
+	}
+	assert.Equal(sig, sigs[0])
+
+	merged, err := MergePackedTransactions(copies...)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(sigs, merged.Signatures)
+	assert.Equal("zlib", merged.Compression)
+
+	//merging again does not duplicate signatures
+	assert.Nil(merged.MergeSignatures(packedTx))
+	assert.Equal(sigs, merged.Signatures)
+
+	r := map[string]interface{}{}
+	json.Unmarshal([]byte(merged.Pack(false)), &r)
+	assert.Equal("none", r["compression"])
+	assert.Equal(hex.EncodeToString(packedTx.rawTx), r["packed_trx"])
+
+	other := newTestPackedTransaction()
+	assert.NotNil(merged.MergeSignatures(other))
+	other.tx.Expiration = packedTx.tx.Expiration
+	assert.NotNil(merged.MergeSignatures(other), "different context free data")
+
+	_, err = MergePackedTransactions()
+	assert.NotNil(err)
+}
+
+func TestVerifyTransaction(t *testing.T) {
+	secp256k1.Init()
+	assert := assert.New(t)
+
+	packedTx := newTestPackedTransaction()
+	assert.Nil(packedTx.AddContextFreeData([]byte("hello")))
+	_, err := packedTx.SignByPrivateKey("5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL")
+	assert.Nil(err)
+	_, err = packedTx.SignByPrivateKey("5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP79zkvFD3")
+	assert.Nil(err)
+
+	r1, err := GeneratePrivateKey(KeyTypeR1)
+	if err != nil {
+		panic(err)
+	}
+	sig, err := packedTx.SignByPrivateKey(r1.String())
+	assert.Nil(err)
+
+	result, err := packedTx.Verify()
+	assert.Nil(err)
+	assert.True(result.IsValid())
+	assert.Equal([]string{
+		"EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV",
+		"EOS6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5GDW5CV",
+		r1.GetPublicKey().String(),
+	}, result.RecoveredKeys)
+	assert.True(result.HasKey("PUB_K1_6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5BoDq63"))
+	other, _ := GeneratePrivateKey(KeyTypeK1)
+	assert.False(result.HasKey(other.GetPublicKey().StringEOS()))
+
+	//signatures of R1 keys are not deterministic
+	sig2, err := packedTx.SignByPrivateKey(r1.String())
+	assert.Nil(err)
+	assert.NotEqual(sig, sig2)
+	packedTx.Signatures = append(packedTx.Signatures, "SIG_K1_bad")
+
+	result, err = packedTx.Verify()
+	assert.Nil(err)
+	assert.False(result.IsValid())
+	assert.Equal(3, len(result.RecoveredKeys))
+	assert.Equal([]string{sig2}, result.DuplicatedSignatures)
+	assert.Equal([]string{"SIG_K1_bad"}, result.InvalidSignatures)
+
+	//the digest includes the context free data
+	unpacked, err := UnpackPackedTransaction(packedTx.Pack(true))
+	if err != nil {
+		panic(err)
+	}
+	_, err = unpacked.Verify()
+	assert.NotNil(err, "chain id is not set")
+	unpacked.SetChainId(testChainInfo["chain_id"].(string))
+	unpacked.contextFreeData = []Bytes{}
+	result, err = unpacked.Verify()
+	assert.Nil(err)
+	assert.False(result.HasKey("EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"))
+
+	//WebAuthn signatures are recovered through the R1 key of the authenticator
+	digest, _ := packedTx.digest()
+	packedTx.Signatures = []string{
+		newTestWebAuthnSignature(r1, digest, "https://example.com:8443"),
+		newTestWebAuthnSignature(r1, make([]byte, 32), "https://example.com"),
+	}
+	result, err = packedTx.Verify()
+	assert.Nil(err)
+	assert.Equal(packedTx.Signatures[1:], result.InvalidSignatures)
+	enc := NewEncoder(64)
+	enc.WriteBytes(r1.GetPublicKey().Data)
+	enc.PackUint8(2)
+	enc.PackString("example.com")
+	waPub := &PublicKey{Type: KeyTypeWA, Data: enc.GetBytes()}
+	assert.Equal([]string{waPub.String()}, result.RecoveredKeys)
+	assert.True(result.HasKey(waPub.String()))
+}
+
+// newTestWebAuthnSignature signs digest as the challenge of a WebAuthn assertion with the authenticator key priv
+func newTestWebAuthnSignature(priv *PrivateKey, digest []byte, origin string) string {
+	rpid := sha256.Sum256([]byte("example.com"))
+	//user present and verified, followed by the signature counter
+	authData := append(rpid[:], 0x05, 0, 0, 0, 1)
+	clientJson := `{"type":"webauthn.get","challenge":"` + base64.RawURLEncoding.EncodeToString(digest) + `","origin":"` + origin + `"}`
+	clientHash := sha256.Sum256([]byte(clientJson))
+	signed := sha256.Sum256(append(append([]byte{}, authData...), clientHash[:]...))
+	sig, err := priv.Sign(signed[:])
+	if err != nil {
+		panic(err)
+	}
+
+	enc := NewEncoder(256)
+	enc.WriteBytes(sig.Data)
+	enc.PackBytes(authData)
+	enc.PackString(clientJson)
+	return (&Signature{Type: KeyTypeWA, Data: enc.GetBytes()}).String()
+}
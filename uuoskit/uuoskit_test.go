@@ -15,6 +15,7 @@ import (
 	"testing"
 
 	"github.com/iancoleman/orderedmap"
+	"github.com/stretchr/testify/assert"
 	secp256k1 "github.com/uuosio/go-secp256k1"
 )
 
@@ -451,6 +452,40 @@ func TestParseAsset(t *testing.T) {
 	t.Log(v)
 }
 
+func TestAssetErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewSymbolE("TOOLONGSYM", 4)
+	assert.Contains(err.Error(), "bad symbol name")
+	_, err = NewSymbolE("EOS", 256)
+	assert.Contains(err.Error(), "bad symbol precision 256")
+	_, err = NewAssetE(1, Symbol{0})
+	assert.Contains(err.Error(), "bad symbol")
+	_, err = NewAssetE(MAX_AMOUNT+1, NewSymbol("EOS", 4))
+	assert.Contains(err.Error(), "magnitude of asset amount")
+	assert.Panics(func() { NewAsset(MAX_AMOUNT+1, NewSymbol("EOS", 4)) })
+
+	//a is left unchanged on error
+	a := NewAsset(MAX_AMOUNT, NewSymbol("EOS", 4))
+	_, err = a.AddE(NewAsset(1, NewSymbol("EOS", 4)))
+	assert.Contains(err.Error(), "addition overflow")
+	assert.Equal(int64(MAX_AMOUNT), a.Amount)
+	_, err = a.SubE(NewAsset(1, NewSymbol("EOS", 4)))
+	assert.Nil(err)
+	assert.Equal(int64(MAX_AMOUNT-1), a.Amount)
+	_, err = a.MulE(NewAsset(2, NewSymbol("EOS", 4)))
+	assert.Contains(err.Error(), "multiplication overflow")
+	_, err = a.DivE(NewAsset(0, NewSymbol("EOS", 4)))
+	assert.Contains(err.Error(), "divide by zero")
+	_, err = a.AddE(NewAsset(1, NewSymbol("EOS", 3)))
+	assert.Contains(err.Error(), "Symbol not the same")
+	assert.Panics(func() { a.Mul(NewAsset(2, NewSymbol("EOS", 4))) })
+	assert.Equal(int64(MAX_AMOUNT-1), a.Amount)
+
+	_, err = NewActionE(NewName("hello"), NewName("sayhello"), "hello")
+	assert.Contains(err.Error(), "third argument not a []PermissionLevel type")
+}
+
 func TestTxMarshal(t *testing.T) {
 	tx := NewTransaction(1122)
 
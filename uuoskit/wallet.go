@@ -1,46 +1,252 @@
 package uuoskit
 
 import (
-	secp256k1 "github.com/uuosio/go-secp256k1"
+	"encoding/json"
+	"os"
+	"time"
 )
 
 type Wallet struct {
-	keys map[string]*secp256k1.PrivateKey
+	keys map[string]*PrivateKey
+
+	//path of the encrypted wallet file, empty for an in-memory wallet
+	path        string
+	salt        []byte
+	cipherKey   []byte
+	locked      bool
+	lockTimeout time.Duration
+	lastAccess  time.Time
+}
+
+//NewWallet creates an in-memory wallet, keys imported into it are lost when the process exits
+func NewWallet() *Wallet {
+	w := &Wallet{}
+	w.keys = make(map[string]*PrivateKey)
+	return w
 }
 
-var gWallet *Wallet
+//CreateWalletFile creates a new encrypted wallet file at path, the returned wallet is unlocked
+func CreateWalletFile(path string, password string) (*Wallet, error) {
+	if len(password) == 0 {
+		return nil, newErrorf("password can not be empty")
+	}
 
-func GetWallet() *Wallet {
-	if gWallet == nil {
-		gWallet = &Wallet{}
-		gWallet.keys = make(map[string]*secp256k1.PrivateKey)
+	if _, err := os.Stat(path); err == nil {
+		return nil, newErrorf("wallet file %s already exists", path)
+	}
+
+	salt, err := newKeystoreSalt()
+	if err != nil {
+		return nil, err
 	}
-	return gWallet
+
+	key, err := deriveKeystoreKey(password, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	w := NewWallet()
+	w.path = path
+	w.salt = salt
+	w.cipherKey = key
+	w.lastAccess = time.Now()
+	if err := w.save(); err != nil {
+		return nil, err
+	}
+	return w, nil
 }
 
-func (w *Wallet) Import(name string, strPriv string) error {
-	priv, err := secp256k1.NewPrivateKeyFromBase58(strPriv)
+//OpenWalletFile opens an existing encrypted wallet file, the returned wallet is locked
+func OpenWalletFile(path string) (*Wallet, error) {
+	ks, err := readKeystoreFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w := NewWallet()
+	w.path = path
+	w.salt = ks.Salt
+	w.locked = true
+	return w, nil
+}
+
+//Path returns the path of the wallet file, or an empty string for an in-memory wallet
+func (w *Wallet) Path() string {
+	return w.path
+}
+
+//Unlock decrypts the private keys stored in the wallet file
+func (w *Wallet) Unlock(password string) error {
+	if w.path == "" {
+		return newErrorf("wallet is not backed by a file")
+	}
+
+	if !w.locked {
+		return newErrorf("wallet is already unlocked")
+	}
+
+	ks, err := readKeystoreFile(w.path)
+	if err != nil {
+		return err
+	}
+
+	key, err := deriveKeystoreKey(password, ks.Salt)
+	if err != nil {
+		return err
+	}
+
+	plain, err := decryptKeystore(key, ks.Nonce, ks.CipherKeys)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(plain)
+
+	privKeys := []string{}
+	if err := json.Unmarshal(plain, &privKeys); err != nil {
+		return newError(err)
+	}
+
+	keys := make(map[string]*PrivateKey, len(privKeys))
+	for _, strPriv := range privKeys {
+		priv, err := NewPrivateKeyFromString(strPriv)
+		if err != nil {
+			return newError(err)
+		}
+		keys[priv.GetPublicKey().StringEOS()] = priv
+	}
+
+	w.keys = keys
+	w.salt = ks.Salt
+	w.cipherKey = key
+	w.locked = false
+	w.lastAccess = time.Now()
+	return nil
+}
+
+//Lock wipes the decrypted private keys from memory
+func (w *Wallet) Lock() error {
+	if w.path == "" {
+		return newErrorf("wallet is not backed by a file")
+	}
+
+	if w.locked {
+		return nil
+	}
+
+	for pubKey, priv := range w.keys {
+		zeroBytes(priv.Data[:])
+		delete(w.keys, pubKey)
+	}
+	zeroBytes(w.cipherKey)
+	w.cipherKey = nil
+	w.locked = true
+	return nil
+}
+
+func (w *Wallet) IsLocked() bool {
+	w.checkLockTimeout()
+	return w.locked
+}
+
+//SetLockTimeout locks the wallet automatically once it has not been used for timeout,
+//a timeout of zero disables auto-locking
+func (w *Wallet) SetLockTimeout(timeout time.Duration) {
+	w.lockTimeout = timeout
+	w.lastAccess = time.Now()
+}
+
+func (w *Wallet) checkLockTimeout() {
+	if w.path == "" || w.locked || w.lockTimeout <= 0 {
+		return
+	}
+
+	if time.Since(w.lastAccess) >= w.lockTimeout {
+		w.Lock()
+	}
+}
+
+//access checks the wallet is usable and refreshes the auto-lock timer
+func (w *Wallet) access() error {
+	w.checkLockTimeout()
+	if w.locked {
+		return newErrorf("wallet is locked")
+	}
+	w.lastAccess = time.Now()
+	return nil
+}
+
+func (w *Wallet) save() error {
+	if w.path == "" {
+		return nil
+	}
+
+	privKeys := make([]string, 0, len(w.keys))
+	for _, priv := range w.keys {
+		privKeys = append(privKeys, priv.String())
+	}
+
+	plain, err := json.Marshal(privKeys)
+	if err != nil {
+		return newError(err)
+	}
+	defer zeroBytes(plain)
+
+	nonce, cipherKeys, err := encryptKeystore(w.cipherKey, plain)
+	if err != nil {
+		return err
+	}
+
+	ks := &keystoreFile{
+		Version:    keystoreVersion,
+		Salt:       w.salt,
+		Nonce:      nonce,
+		CipherKeys: cipherKeys,
+	}
+	return writeKeystoreFile(w.path, ks)
+}
+
+func (w *Wallet) Import(strPriv string) error {
+	if err := w.access(); err != nil {
+		return err
+	}
+
+	priv, err := NewPrivateKeyFromString(strPriv)
 	if err != nil {
 		return newError(err)
 	}
 
 	pub := priv.GetPublicKey()
-	w.keys[pub.StringEOS()] = priv
+	pubKey := pub.StringEOS()
+	if _, ok := w.keys[pubKey]; ok {
+		return nil
+	}
+
+	w.keys[pubKey] = priv
+	if err := w.save(); err != nil {
+		delete(w.keys, pubKey)
+		return err
+	}
 	return nil
 }
 
-func (w *Wallet) Remove(name string, pubKey string) bool {
-	_pubKey, err := secp256k1.NewPublicKeyFromBase58(pubKey)
+func (w *Wallet) Remove(pubKey string) bool {
+	if err := w.access(); err != nil {
+		return false
+	}
+
+	_pubKey, err := NewPublicKeyFromString(pubKey)
 	if err != nil {
 		return false
 	}
 
 	pubKey = _pubKey.StringEOS()
 	if priv, ok := w.keys[pubKey]; ok {
-		for i := 0; i < len(w.keys); i++ {
-			priv.Data[i] = 0
-		}
 		delete(w.keys, pubKey)
+		if err := w.save(); err != nil {
+			w.keys[pubKey] = priv
+			return false
+		}
+		zeroBytes(priv.Data[:])
 		return true
 	}
 	return false
@@ -48,6 +254,10 @@ func (w *Wallet) Remove(name string, pubKey string) bool {
 
 //GetPublicKeys
 func (w *Wallet) GetPublicKeys() []string {
+	if err := w.access(); err != nil {
+		return []string{}
+	}
+
 	keys := make([]string, 0, len(w.keys))
 	for k := range w.keys {
 		keys = append(keys, k)
@@ -55,7 +265,11 @@ func (w *Wallet) GetPublicKeys() []string {
 	return keys
 }
 
-func (w *Wallet) GetPrivateKey(pubKey string) (*secp256k1.PrivateKey, error) {
+func (w *Wallet) GetPrivateKey(pubKey string) (*PrivateKey, error) {
+	if err := w.access(); err != nil {
+		return nil, err
+	}
+
 	priv, ok := w.keys[pubKey]
 	if !ok {
 		return nil, newErrorf("not found")
@@ -63,8 +277,12 @@ func (w *Wallet) GetPrivateKey(pubKey string) (*secp256k1.PrivateKey, error) {
 	return priv, nil
 }
 
-func (w *Wallet) Sign(digest []byte, pubKey string) (*secp256k1.Signature, error) {
-	pub, err := secp256k1.NewPublicKeyFromBase58(pubKey)
+func (w *Wallet) Sign(digest []byte, pubKey string) (*Signature, error) {
+	if err := w.access(); err != nil {
+		return nil, err
+	}
+
+	pub, err := NewPublicKeyFromString(pubKey)
 	if err != nil {
 		return nil, newError(err)
 	}
@@ -79,3 +297,9 @@ func (w *Wallet) Sign(digest []byte, pubKey string) (*secp256k1.Signature, error
 	}
 	return sig, nil
 }
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,161 @@
+package uuoskit
+
+import (
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	secp256k1 "github.com/uuosio/go-secp256k1"
+)
+
+func TestWalletFile(t *testing.T) {
+	secp256k1.Init()
+	assert := assert.New(t)
+
+	priv := "5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL"
+	pub := "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"
+	path := filepath.Join(t.TempDir(), "test.wallet")
+
+	w, err := CreateWalletFile(path, "123456")
+	if err != nil {
+		panic(err)
+	}
+	assert.False(w.IsLocked())
+
+	_, err = CreateWalletFile(path, "123456")
+	assert.NotNil(err, "wallet file should not be overwritten")
+
+	err = w.Import(priv)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal([]string{pub}, w.GetPublicKeys())
+
+	w, err = OpenWalletFile(path)
+	if err != nil {
+		panic(err)
+	}
+	assert.True(w.IsLocked())
+	assert.Equal([]string{}, w.GetPublicKeys())
+	assert.NotNil(w.Import(priv), "import into a locked wallet")
+
+	assert.NotNil(w.Unlock("654321"), "unlock with wrong password")
+	assert.True(w.IsLocked())
+
+	err = w.Unlock("123456")
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal([]string{pub}, w.GetPublicKeys())
+
+	digest, _ := hex.DecodeString("2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+	_, err = w.Sign(digest, pub)
+	assert.Nil(err)
+
+	err = w.Lock()
+	if err != nil {
+		panic(err)
+	}
+	_, err = w.Sign(digest, pub)
+	assert.NotNil(err, "sign with a locked wallet")
+
+	assert.Nil(w.Unlock("123456"))
+	assert.True(w.Remove(pub))
+	assert.Nil(w.Lock())
+	assert.Nil(w.Unlock("123456"))
+	assert.Equal([]string{}, w.GetPublicKeys())
+}
+
+func TestWalletLockTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "test.wallet")
+	w, err := CreateWalletFile(path, "123456")
+	if err != nil {
+		panic(err)
+	}
+
+	w.SetLockTimeout(10 * time.Millisecond)
+	assert.False(w.IsLocked())
+	time.Sleep(20 * time.Millisecond)
+	assert.True(w.IsLocked())
+
+	assert.NotNil(NewWallet().Lock(), "in-memory wallet can not be locked")
+}
+
+func TestLoadWallet(t *testing.T) {
+	assert := assert.New(t)
+	//other tests import keys to a wallet named test in the global wallet manager
+	saved := gWalletManager
+	gWalletManager = nil
+	defer func() { gWalletManager = saved }()
+
+	path := filepath.Join(t.TempDir(), "test.wallet")
+	_, err := CreateWalletFile(path, "123456")
+	if err != nil {
+		panic(err)
+	}
+
+	w, err := LoadWallet(path)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal([]string{"test"}, GetWallet().List())
+	assert.True(w.IsLocked())
+	assert.Nil(GetWallet().Unlock("test", "123456"))
+	assert.False(w.IsLocked())
+}
+
+func TestWalletManager(t *testing.T) {
+	secp256k1.Init()
+	assert := assert.New(t)
+
+	priv1 := "5JRYimgLBrRLCBAcjHUWCYRv3asNedTYYzVgmiU4q2ZVxMBiJXL"
+	pub1 := "EOS6AjF6hvF7GSuSd4sCgfPKq5uWaXvGM2aQtEUCwmEHygQaqxBSV"
+	priv2 := "5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP79zkvFD3"
+	pub2 := "EOS6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5GDW5CV"
+
+	m := NewWalletManager(t.TempDir())
+	_, err := m.Create("alice", "123456")
+	if err != nil {
+		panic(err)
+	}
+	assert.Nil(m.Import("alice", priv1))
+	assert.Nil(m.Import("bob", priv2))
+	assert.Equal([]string{"alice", "bob"}, m.List())
+	assert.ElementsMatch([]string{pub1, pub2}, m.GetPublicKeys())
+
+	_, err = m.Create("bob", "123456")
+	assert.NotNil(err, "wallet name already in use")
+
+	assert.Nil(m.Select("bob"))
+	assert.Equal([]string{pub2}, m.GetPublicKeys())
+	_, err = m.GetPrivateKey(pub1)
+	assert.NotNil(err, "key of an unselected wallet")
+	_, err = m.GetPrivateKey("PUB_K1_6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5BoDq63")
+	assert.Nil(err)
+	assert.NotNil(m.Select("carol"))
+
+	assert.Nil(m.Select())
+	assert.Nil(m.Lock("alice"))
+	assert.Equal([]string{pub2}, m.GetPublicKeys())
+
+	assert.Nil(m.Close("alice"))
+	files, err := m.ListWalletFiles()
+	assert.Nil(err)
+	assert.Equal([]string{"alice"}, files)
+
+	w, err := m.Open("alice")
+	if err != nil {
+		panic(err)
+	}
+	assert.True(w.IsLocked())
+	assert.Nil(m.Unlock("alice", "123456"))
+	assert.ElementsMatch([]string{pub1, pub2}, m.GetPublicKeys())
+
+	assert.True(m.Remove("bob", pub2))
+	assert.False(m.Remove("carol", pub2))
+	assert.Equal([]string{pub1}, m.GetPublicKeys())
+}
@@ -0,0 +1,362 @@
+package uuoskit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultWalletName    = "default"
+	walletFileSuffix     = ".wallet"
+	defaultWalletDirName = "uuoskit-wallet"
+)
+
+// WalletManager holds several named wallets.
+// Wallets created with Create or opened with Open are stored as encrypted files in the wallet directory,
+// importing a key into an unknown wallet name creates an in-memory wallet with that name.
+type WalletManager struct {
+	dir         string
+	wallets     map[string]*Wallet
+	selected    []string
+	lockTimeout time.Duration
+}
+
+var gWalletManager *WalletManager
+
+// GetWallet returns the global wallet manager used for signing transactions
+func GetWallet() *WalletManager {
+	if gWalletManager == nil {
+		gWalletManager = NewWalletManager("")
+	}
+	return gWalletManager
+}
+
+// LoadWallet opens the encrypted wallet file at path and adds it to the global wallet manager,
+// the wallet is named after the file name without the .wallet suffix and stays locked until unlocked.
+func LoadWallet(path string) (*Wallet, error) {
+	return GetWallet().Load(path)
+}
+
+// NewWalletManager creates a wallet manager that keeps its wallet files in dir,
+// an empty dir means ~/uuoskit-wallet
+func NewWalletManager(dir string) *WalletManager {
+	m := &WalletManager{}
+	m.dir = dir
+	m.wallets = make(map[string]*Wallet)
+	return m
+}
+
+func (m *WalletManager) SetWalletDir(dir string) {
+	m.dir = dir
+}
+
+func (m *WalletManager) GetWalletDir() (string, error) {
+	if m.dir != "" {
+		return m.dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", newError(err)
+	}
+	return filepath.Join(home, defaultWalletDirName), nil
+}
+
+func (m *WalletManager) walletPath(name string) (string, error) {
+	if err := validateWalletName(name); err != nil {
+		return "", err
+	}
+
+	dir, err := m.GetWalletDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+walletFileSuffix), nil
+}
+
+func validateWalletName(name string) error {
+	if name == "" {
+		return newErrorf("wallet name can not be empty")
+	}
+
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.') {
+			return newErrorf("invalid wallet name %s", name)
+		}
+	}
+	return nil
+}
+
+// Create creates a new encrypted wallet file in the wallet directory, the new wallet is unlocked
+func (m *WalletManager) Create(name string, password string) (*Wallet, error) {
+	if _, ok := m.wallets[name]; ok {
+		return nil, newErrorf("wallet %s already exists", name)
+	}
+
+	path, err := m.walletPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, newError(err)
+	}
+
+	w, err := CreateWalletFile(path, password)
+	if err != nil {
+		return nil, err
+	}
+	w.SetLockTimeout(m.lockTimeout)
+	m.wallets[name] = w
+	return w, nil
+}
+
+// Open opens the wallet file with name from the wallet directory, the wallet is locked
+func (m *WalletManager) Open(name string) (*Wallet, error) {
+	if w, ok := m.wallets[name]; ok {
+		return w, nil
+	}
+
+	path, err := m.walletPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := OpenWalletFile(path)
+	if err != nil {
+		return nil, err
+	}
+	w.SetLockTimeout(m.lockTimeout)
+	m.wallets[name] = w
+	return w, nil
+}
+
+// Load opens a wallet file outside of the wallet directory
+func (m *WalletManager) Load(path string) (*Wallet, error) {
+	name := strings.TrimSuffix(filepath.Base(path), walletFileSuffix)
+	if err := validateWalletName(name); err != nil {
+		return nil, err
+	}
+
+	if _, ok := m.wallets[name]; ok {
+		return nil, newErrorf("wallet %s already exists", name)
+	}
+
+	w, err := OpenWalletFile(path)
+	if err != nil {
+		return nil, err
+	}
+	w.SetLockTimeout(m.lockTimeout)
+	m.wallets[name] = w
+	return w, nil
+}
+
+// Close locks the wallet and removes it from the manager, the wallet file is kept
+func (m *WalletManager) Close(name string) error {
+	w, ok := m.wallets[name]
+	if !ok {
+		return newErrorf("wallet %s not found", name)
+	}
+
+	if w.Path() != "" {
+		if err := w.Lock(); err != nil {
+			return err
+		}
+	}
+	delete(m.wallets, name)
+	return nil
+}
+
+func (m *WalletManager) GetWallet(name string) (*Wallet, error) {
+	w, ok := m.wallets[name]
+	if !ok {
+		return nil, newErrorf("wallet %s not found", name)
+	}
+	return w, nil
+}
+
+// List returns the names of all opened wallets
+func (m *WalletManager) List() []string {
+	names := make([]string, 0, len(m.wallets))
+	for name := range m.wallets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ListWalletFiles returns the names of the wallet files in the wallet directory
+func (m *WalletManager) ListWalletFiles() ([]string, error) {
+	dir, err := m.GetWalletDir()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, newError(err)
+	}
+
+	names := []string{}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), walletFileSuffix) {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(f.Name(), walletFileSuffix))
+	}
+	return names, nil
+}
+
+// Select limits the wallets used for signing to names, calling Select without names makes all wallets eligible
+func (m *WalletManager) Select(names ...string) error {
+	for _, name := range names {
+		if _, ok := m.wallets[name]; !ok {
+			return newErrorf("wallet %s not found", name)
+		}
+	}
+
+	if len(names) == 0 {
+		m.selected = nil
+	} else {
+		m.selected = append([]string{}, names...)
+	}
+	return nil
+}
+
+// GetSelected returns the names of the wallets used for signing
+func (m *WalletManager) GetSelected() []string {
+	if m.selected == nil {
+		return m.List()
+	}
+	return append([]string{}, m.selected...)
+}
+
+func (m *WalletManager) selectedWallets() []*Wallet {
+	names := m.GetSelected()
+	wallets := make([]*Wallet, 0, len(names))
+	for _, name := range names {
+		if w, ok := m.wallets[name]; ok {
+			wallets = append(wallets, w)
+		}
+	}
+	return wallets
+}
+
+func (m *WalletManager) Unlock(name string, password string) error {
+	w, err := m.GetWallet(name)
+	if err != nil {
+		return err
+	}
+	return w.Unlock(password)
+}
+
+func (m *WalletManager) Lock(name string) error {
+	w, err := m.GetWallet(name)
+	if err != nil {
+		return err
+	}
+	return w.Lock()
+}
+
+// LockAll locks every wallet that is backed by a file
+func (m *WalletManager) LockAll() error {
+	for _, w := range m.wallets {
+		if w.Path() == "" {
+			continue
+		}
+		if err := w.Lock(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetLockTimeout sets the auto-lock timeout of all opened wallets and of the wallets opened later
+func (m *WalletManager) SetLockTimeout(timeout time.Duration) {
+	m.lockTimeout = timeout
+	for _, w := range m.wallets {
+		w.SetLockTimeout(timeout)
+	}
+}
+
+// Import imports a private key into the wallet with name,
+// an in-memory wallet is created if no wallet with that name has been opened
+func (m *WalletManager) Import(name string, strPriv string) error {
+	if name == "" {
+		name = DefaultWalletName
+	}
+
+	w, ok := m.wallets[name]
+	if !ok {
+		if err := validateWalletName(name); err != nil {
+			return err
+		}
+		w = NewWallet()
+		m.wallets[name] = w
+	}
+	return w.Import(strPriv)
+}
+
+func (m *WalletManager) Remove(name string, pubKey string) bool {
+	if name == "" {
+		name = DefaultWalletName
+	}
+
+	w, ok := m.wallets[name]
+	if !ok {
+		return false
+	}
+	return w.Remove(pubKey)
+}
+
+// GetPublicKeys returns the public keys of all selected and unlocked wallets
+func (m *WalletManager) GetPublicKeys() []string {
+	keys := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, w := range m.selectedWallets() {
+		for _, key := range w.GetPublicKeys() {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (m *WalletManager) GetPrivateKey(pubKey string) (*PrivateKey, error) {
+	if pub, err := NewPublicKeyFromString(pubKey); err == nil {
+		pubKey = pub.StringEOS()
+	}
+
+	for _, w := range m.selectedWallets() {
+		if w.IsLocked() {
+			continue
+		}
+		if priv, err := w.GetPrivateKey(pubKey); err == nil {
+			return priv, nil
+		}
+	}
+	return nil, newErrorf("not found")
+}
+
+func (m *WalletManager) Sign(digest []byte, pubKey string) (*Signature, error) {
+	priv, err := m.GetPrivateKey(pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := priv.Sign(digest)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return sig, nil
+}